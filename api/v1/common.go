@@ -20,4 +20,7 @@ const (
 	ProjectResourceQuotaFinalizer = "jenting.io/finalizer"
 
 	ProjectResourceQuotaAnnotation = "project-resource-quota"
+
+	// ControllerFieldManager is the field manager of the patches written by the controller
+	ControllerFieldManager = "projectresourcequota-controller"
 )
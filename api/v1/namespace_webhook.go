@@ -0,0 +1,97 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func SetupNamespaceWebhookWithManager(mgr ctrl.Manager) error {
+	return registerValidator(mgr, "/validate--v1-namespace", &corev1.Namespace{}, &namespaceValidator{mgr.GetClient()})
+}
+
+//+kubebuilder:webhook:path=/validate--v1-namespace,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",matchPolicy=Exact,resources=namespaces,verbs=create;delete,versions=v1,name=namespace.jenting.io,admissionReviewVersions=v1
+
+// namespaceValidator validates the Namespaces within the projects
+type namespaceValidator struct {
+	client.Client
+}
+
+// projectResourceQuotas returns the ProjectResourceQuotas the namespace belongs to and their ancestors
+func (v *namespaceValidator) projectResourceQuotas(ctx context.Context, ns *corev1.Namespace) ([]*ProjectResourceQuota, error) {
+	prqList := &ProjectResourceQuotaList{}
+	if err := v.List(ctx, prqList); err != nil {
+		return nil, err
+	}
+	return matchProjectResourceQuotas(prqList.Items, ns)
+}
+
+func (v *namespaceValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (Warnings, error) {
+	log := logf.FromContext(ctx)
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return nil, fmt.Errorf("expected a Namespace but got a %T", obj)
+	}
+
+	log.Info("Validating Namespace creation")
+	prqs, err := v.projectResourceQuotas(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	// the namespace joins the projects once created, the objects within it count against the projects
+	var warnings Warnings
+	for _, prq := range prqs {
+		warnings = append(warnings, fmt.Sprintf("namespace %s joins project resource quota %s", ns.Name, prq.Name))
+	}
+	return warnings, nil
+}
+
+func (v *namespaceValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (Warnings, error) {
+	return nil, nil
+}
+
+func (v *namespaceValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (Warnings, error) {
+	log := logf.FromContext(ctx)
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return nil, fmt.Errorf("expected a Namespace but got a %T", obj)
+	}
+
+	log.Info("Validating Namespace deletion")
+	prqs, err := v.projectResourceQuotas(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	// deny the deletion of the namespace still within a project protecting its namespaces
+	var warnings Warnings
+	for _, prq := range prqs {
+		if prq.Spec.ProtectNamespaces {
+			return nil, fmt.Errorf("namespace %s is within project resource quota %s which protects its namespaces, remove the namespace from the project first", ns.Name, prq.Name)
+		}
+		warnings = append(warnings, fmt.Sprintf("namespace %s leaves project resource quota %s", ns.Name, prq.Name))
+	}
+	return warnings, nil
+}
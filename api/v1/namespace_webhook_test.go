@@ -0,0 +1,56 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Namespace", func() {
+	It("should not delete the namespace within a project protecting its namespaces", func() {
+		name := "namespace-protected"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+		prq := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces:        []string{name},
+				ProtectNamespaces: true,
+				Hard:              corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		Eventually(func() error {
+			return k8sClient.Delete(ctx, ns, client.DryRunAll)
+		}).ShouldNot(Succeed())
+
+		// the namespace removed from the project can be deleted
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		prq.Spec.Namespaces = []string{name + "-other"}
+		Expect(k8sClient.Update(ctx, prq)).To(Succeed())
+		Eventually(func() error {
+			return k8sClient.Delete(ctx, ns, client.DryRunAll)
+		}).Should(Succeed())
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/jenting/projectresourcequota/internal/quota"
+)
+
+// objectCountWebhookPath is the path of the webhook validating the count/<resource>.<group> resources
+const objectCountWebhookPath = "/validate-count"
+
+func SetupObjectCountWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(objectCountWebhookPath, &webhook.Admission{
+		Handler: &objectCountValidator{mgr.GetClient(), newQuotaReserver(mgr)},
+	})
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-count,mutating=false,failurePolicy=ignore,sideEffects=NoneOnDryRun,groups=*,resources=*,verbs=create,versions=*,name=objectcount.jenting.io,admissionReviewVersions=v1
+
+// objectCountValidator validates the creation of any namespaced object against the count/<resource>.<group> resources.
+// The objects are not annotated, every object within the project namespaces is counted as the Kubernetes does.
+type objectCountValidator struct {
+	client.Client
+	reserver *quotaReserver
+}
+
+func (v *objectCountValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	log := logf.FromContext(ctx)
+
+	// the cluster-scoped objects are not within any project
+	if len(req.Namespace) == 0 {
+		return admission.Allowed("")
+	}
+	// the events are created at a high rate by the cluster components, they are not validated
+	if req.Resource.Resource == "events" {
+		return admission.Allowed("")
+	}
+
+	// find the projectresourcequotas.jenting.io CRs the namespace belongs to which have spec.hard.count/<resource>.<group> set
+	prqs, err := GetProjectResourceQuotas(ctx, v.Client, req.Namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	resourceName := quota.ObjectCountQuotaResourceNameFor(schema.GroupResource{Group: req.Resource.Group, Resource: req.Resource.Resource})
+	var prqNames []string
+	for _, prq := range prqs {
+		if _, found := prq.Spec.Hard[resourceName]; found {
+			prqNames = append(prqNames, prq.Name)
+		}
+	}
+	if len(prqNames) == 0 {
+		return admission.Allowed("")
+	}
+
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	obj.SetNamespace(req.Namespace)
+
+	log.Info("Validating object creation", "resource", resourceName)
+	// reserve the count/<resource>.<group> usage in the projectresourcequotas.jenting.io CRs
+	ctx = admission.NewContextWithRequest(ctx, req)
+	warnings, err := v.reserver.reserve(ctx, prqNames, req.Kind.Kind, obj, corev1.ResourceList{resourceName: resource.MustParse("1")})
+	if err != nil {
+		return admission.Denied(err.Error()).WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jenting/projectresourcequota/internal/quota"
+)
+
+// MatchNamespace returns whether the namespace is listed in spec.namespaces or selected by spec.namespaceSelector
+func (prq *ProjectResourceQuota) MatchNamespace(ns *corev1.Namespace) (bool, error) {
+	for _, namespace := range prq.Spec.Namespaces {
+		if namespace == ns.Name {
+			return true, nil
+		}
+	}
+
+	if prq.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(prq.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// ProjectNamespaces returns the namespaces within the project among the given namespaces.
+// The namespaces listed in spec.namespaces are always returned, even if they do not exist yet.
+func (prq *ProjectResourceQuota) ProjectNamespaces(namespaces []corev1.Namespace) (sets.String, error) {
+	projectNamespaces := sets.NewString(prq.Spec.Namespaces...)
+	if prq.Spec.NamespaceSelector == nil {
+		return projectNamespaces, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(prq.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range namespaces {
+		if selector.Matches(labels.Set(ns.Labels)) {
+			projectNamespaces.Insert(ns.Name)
+		}
+	}
+	return projectNamespaces, nil
+}
+
+// GetProjectNamespaces resolves the namespaces within the project,
+// including the namespaces within the descendant projects whose usage rolls up into the project.
+func GetProjectNamespaces(ctx context.Context, c client.Reader, prq *ProjectResourceQuota) (sets.String, error) {
+	prqList := &ProjectResourceQuotaList{}
+	if err := c.List(ctx, prqList); err != nil {
+		return nil, err
+	}
+
+	var nsList *corev1.NamespaceList
+	projectNamespaces := sets.NewString()
+	for _, project := range append([]*ProjectResourceQuota{prq}, prq.Descendants(prqList.Items)...) {
+		// list the namespaces only when there is a namespace selector to match
+		if project.Spec.NamespaceSelector != nil && nsList == nil {
+			nsList = &corev1.NamespaceList{}
+			if err := c.List(ctx, nsList); err != nil {
+				return nil, err
+			}
+		}
+
+		var namespaces []corev1.Namespace
+		if nsList != nil {
+			namespaces = nsList.Items
+		}
+		ns, err := project.ProjectNamespaces(namespaces)
+		if err != nil {
+			return nil, err
+		}
+		projectNamespaces = projectNamespaces.Union(ns)
+	}
+	return projectNamespaces, nil
+}
+
+// Descendants returns the descendant ProjectResourceQuotas among the given ProjectResourceQuotas
+func (prq *ProjectResourceQuota) Descendants(prqs []ProjectResourceQuota) []*ProjectResourceQuota {
+	var descendants []*ProjectResourceQuota
+	visited := sets.NewString(prq.Name)
+	parents := []string{prq.Name}
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+		for i := range prqs {
+			child := &prqs[i]
+			if child.Spec.Parent != parent || visited.Has(child.Name) {
+				continue
+			}
+			visited.Insert(child.Name)
+			descendants = append(descendants, child)
+			parents = append(parents, child.Name)
+		}
+	}
+	return descendants
+}
+
+// Ancestors returns the ancestor ProjectResourceQuotas among the given ProjectResourceQuotas, the parent first
+func (prq *ProjectResourceQuota) Ancestors(prqs []ProjectResourceQuota) []*ProjectResourceQuota {
+	byName := map[string]*ProjectResourceQuota{}
+	for i := range prqs {
+		byName[prqs[i].Name] = &prqs[i]
+	}
+
+	var ancestors []*ProjectResourceQuota
+	visited := sets.NewString(prq.Name)
+	for parent := prq.Spec.Parent; len(parent) > 0 && !visited.Has(parent); {
+		ancestor, found := byName[parent]
+		if !found {
+			break
+		}
+		visited.Insert(parent)
+		ancestors = append(ancestors, ancestor)
+		parent = ancestor.Spec.Parent
+	}
+	return ancestors
+}
+
+// GetProjectResourceQuotas returns the ProjectResourceQuotas the namespace belongs to and their ancestors,
+// a namespace might belong to several projects, e.g. a department project and a team project.
+// The namespace which does not exist belongs to the projects listing it in spec.namespaces only.
+func GetProjectResourceQuotas(ctx context.Context, c client.Reader, namespace string) ([]*ProjectResourceQuota, error) {
+	prqList := &ProjectResourceQuotaList{}
+	if err := c.List(ctx, prqList); err != nil {
+		return nil, err
+	}
+
+	// get the namespace labels only when there is a namespace selector to match
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	for _, prq := range prqList.Items {
+		if prq.Spec.NamespaceSelector != nil && prq.DeletionTimestamp == nil {
+			if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); client.IgnoreNotFound(err) != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return matchProjectResourceQuotas(prqList.Items, ns)
+}
+
+// matchProjectResourceQuotas returns the ProjectResourceQuotas the namespace belongs to and their ancestors among the given ProjectResourceQuotas
+func matchProjectResourceQuotas(prqs []ProjectResourceQuota, ns *corev1.Namespace) ([]*ProjectResourceQuota, error) {
+	found := sets.NewString()
+	var matchedPrqs []*ProjectResourceQuota
+	for i := range prqs {
+		prq := &prqs[i]
+
+		// skip the projectresourcequota CR that is being deleted
+		if prq.DeletionTimestamp != nil {
+			continue
+		}
+
+		matched, err := prq.MatchNamespace(ns)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+
+		// the usage within the project rolls up into the ancestor projects
+		for _, project := range append([]*ProjectResourceQuota{prq}, prq.Ancestors(prqs)...) {
+			if project.DeletionTimestamp != nil || found.Has(project.Name) {
+				continue
+			}
+			found.Insert(project.Name)
+			matchedPrqs = append(matchedPrqs, project)
+		}
+	}
+	return matchedPrqs, nil
+}
+
+// AttributableProjectResourceQuotas returns the names of the ProjectResourceQuotas among the given ProjectResourceQuotas the object is attributed to,
+// i.e. the ones tracking any resource of the object kind whose spec.scopes and spec.scopeSelector the object matches.
+func AttributableProjectResourceQuotas(evaluator quota.Evaluator, obj client.Object, prqs []*ProjectResourceQuota) (sets.String, error) {
+	names := sets.NewString()
+	for _, prq := range prqs {
+		if !quota.HandlesAny(evaluator, prq.Spec.Hard) {
+			continue
+		}
+
+		matched, err := evaluator.Matches(obj, prq.Spec.Scopes, prq.Spec.ScopeSelector)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			names.Insert(prq.Name)
+		}
+	}
+	return names, nil
+}
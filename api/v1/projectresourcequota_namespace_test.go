@@ -0,0 +1,63 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ProjectResourceQuota namespaces", func() {
+	It("should resolve the namespaces listed in or selected by the project", func() {
+		name := "namespace-selector"
+		listed := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name + "-listed"}}
+		selected := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name + "-selected", Labels: map[string]string{"team": name}}}
+		other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name + "-other", Labels: map[string]string{"team": "other"}}}
+		for _, ns := range []*corev1.Namespace{listed, selected, other} {
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		}
+
+		prq := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces:        []string{listed.Name, name + "-missing"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": name}},
+			},
+		}
+
+		// the union of spec.namespaces, including the namespaces not created yet, and the selected namespaces
+		namespaces, err := GetProjectNamespaces(ctx, k8sClient, prq)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespaces.List()).To(Equal([]string{listed.Name, name + "-missing", selected.Name}))
+
+		for ns, want := range map[*corev1.Namespace]bool{listed: true, selected: true, other: false} {
+			matched, err := prq.MatchNamespace(ns)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(Equal(want), ns.Name)
+		}
+
+		// the invalid selector fails rather than matching no namespace
+		prq.Spec.NamespaceSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Bogus"}}}
+		_, err = prq.MatchNamespace(other)
+		Expect(err).To(HaveOccurred())
+		_, err = GetProjectNamespaces(ctx, k8sClient, prq)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -19,23 +19,171 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+//+kubebuilder:validation:Enum=deny;warn;dryrun
+
+// EnforcementAction is the action taken when an object exceeds the hard limits
+type EnforcementAction string
+
+const (
+	// EnforcementActionDeny denies the object exceeding the hard limits
+	EnforcementActionDeny EnforcementAction = "deny"
+	// EnforcementActionWarn admits the object exceeding the hard limits with a warning
+	EnforcementActionWarn EnforcementAction = "warn"
+	// EnforcementActionDryRun admits the object exceeding the hard limits and records the violation only
+	EnforcementActionDryRun EnforcementAction = "dryrun"
+)
+
+// MaxViolations is the number of the latest violations kept in status.violations
+const MaxViolations = 10
+
 // ProjectResourceQuotaSpec defines the desired state of ProjectResourceQuota
 type ProjectResourceQuotaSpec struct {
-	//+required
-	Namespaces []string `json:"namespaces"`
+	// Namespaces is the list of namespaces that belong to the project.
+	//+optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector selects the namespaces that belong to the project by labels,
+	// in addition to the namespaces listed in spec.namespaces.
+	//+optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ProtectNamespaces denies the deletion of the namespaces within the project
+	//+optional
+	ProtectNamespaces bool `json:"protectNamespaces,omitempty"`
+	// Parent is the name of the parent ProjectResourceQuota, e.g. the department project of a team project.
+	// The usage within the project rolls up into the parent, and the admission is checked against every ancestor.
+	//+optional
+	Parent string `json:"parent,omitempty"`
 	//+optional
 	Hard corev1.ResourceList `json:"hard,omitempty"`
+	// Soft is the soft limits of the resources set in spec.hard, the object exceeding the soft limit
+	// is admitted with a warning, and the SoftLimitExceeded condition is set.
+	//+optional
+	Soft corev1.ResourceList `json:"soft,omitempty"`
+	// Scopes is a collection of filters that must match each pod tracked by the project resource quota,
+	// the supported scopes are Terminating, NotTerminating, BestEffort, NotBestEffort and PriorityClass.
+	//+optional
+	Scopes []corev1.ResourceQuotaScope `json:"scopes,omitempty"`
+	// ScopeSelector is a collection of filters like scopes expressed with operators and values,
+	// a pod is tracked only if it matches both the scopes and the scope selector.
+	//+optional
+	ScopeSelector *corev1.ScopeSelector `json:"scopeSelector,omitempty"`
+	// EnforcementAction is the action taken when an object exceeds the hard limits, one of deny, warn or dryrun.
+	// The warn and dryrun actions admit the object and record the violation in status.violations and the metrics,
+	// the warn action returns an admission warning as well. Defaults to deny.
+	//+optional
+	//+kubebuilder:default=deny
+	EnforcementAction EnforcementAction `json:"enforcementAction,omitempty"`
+}
+
+// GetEnforcementAction returns the enforcement action, defaults to deny
+func (prq *ProjectResourceQuota) GetEnforcementAction() EnforcementAction {
+	if len(prq.Spec.EnforcementAction) == 0 {
+		return EnforcementActionDeny
+	}
+	return prq.Spec.EnforcementAction
 }
 
 // ProjectResourceQuotaStatus defines the observed state of ProjectResourceQuota
 type ProjectResourceQuotaStatus struct {
 	//+optional
 	Used corev1.ResourceList `json:"used,omitempty" protobuf:"bytes,2,rep,name=used,casttype=ResourceList,castkey=ResourceName"`
+	// Reservations are the usages charged to status.used by the admission webhooks
+	// for the admitted objects that the controller has not counted yet.
+	//+optional
+	Reservations []ProjectResourceQuotaReservation `json:"reservations,omitempty"`
+	// Violations are the latest admitted objects exceeding the hard limits with the warn or dryrun enforcement action
+	//+optional
+	Violations []ProjectResourceQuotaViolation `json:"violations,omitempty"`
+	// MissingNamespaces are the namespaces listed in spec.namespaces which do not exist
+	//+optional
+	MissingNamespaces []string `json:"missingNamespaces,omitempty"`
+	// Namespaces is the used resources per namespace within the project, the controller diffs the namespaces
+	// reconciled last time against the current namespaces to clean up the namespaces that left the project
+	//+optional
+	//+listType=map
+	//+listMapKey=namespace
+	Namespaces []ProjectResourceQuotaNamespaceStatus `json:"namespaces,omitempty"`
+	// ObservedGeneration is the spec generation the status.used is calculated for
+	//+optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastReconcileTime is the last time the controller reconciled the ProjectResourceQuota
+	//+optional
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+	// Conditions are the latest observations of the ProjectResourceQuota state
+	//+optional
+	//+patchMergeKey=type
+	//+patchStrategy=merge
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+const (
+	// ConditionReady indicates the controller has calculated the status.used of the current spec
+	ConditionReady = "Ready"
+	// ConditionOverQuota indicates the status.used exceeds the spec.hard,
+	// e.g. the spec.hard is lowered or the objects existed before the project
+	ConditionOverQuota = "OverQuota"
+	// ConditionSoftLimitExceeded indicates the status.used exceeds the spec.soft
+	ConditionSoftLimitExceeded = "SoftLimitExceeded"
+	// ConditionNamespacesMissing indicates some namespaces listed in spec.namespaces do not exist
+	ConditionNamespacesMissing = "NamespacesMissing"
+	// ConditionDegraded indicates the controller failed to reconcile, e.g. it failed to list or annotate the objects
+	ConditionDegraded = "Degraded"
+)
+
+// ProjectResourceQuotaNamespaceStatus is the used resources within a namespace of the project
+type ProjectResourceQuotaNamespaceStatus struct {
+	// Namespace is the namespace name
+	Namespace string `json:"namespace"`
+	// Used is the used resources within the namespace
+	//+optional
+	Used corev1.ResourceList `json:"used,omitempty"`
+}
+
+// ProjectResourceQuotaReservation is the usage reserved by an admitted object
+type ProjectResourceQuotaReservation struct {
+	// UID is the admitted object UID
+	UID types.UID `json:"uid"`
+	// Kind is the admitted object kind
+	Kind string `json:"kind"`
+	// Namespace is the admitted object namespace
+	Namespace string `json:"namespace"`
+	// Name is the admitted object name
+	Name string `json:"name"`
+	// ResourceVersion is the admitted object resource version before the admission,
+	// the controller counts the object once it observes a different resource version.
+	//+optional
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// Usage is the usage reserved by the admitted object
+	//+optional
+	Usage corev1.ResourceList `json:"usage,omitempty"`
+	// CreationTimestamp is the time the usage is reserved
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+}
+
+// ProjectResourceQuotaViolation is an admitted object exceeding the hard limits
+type ProjectResourceQuotaViolation struct {
+	// Kind is the admitted object kind
+	Kind string `json:"kind"`
+	// Namespace is the admitted object namespace
+	Namespace string `json:"namespace"`
+	// Name is the admitted object name
+	Name string `json:"name"`
+	// ResourceName is the resource exceeding the hard limit
+	ResourceName corev1.ResourceName `json:"resourceName"`
+	// EnforcementAction is the enforcement action the object was admitted with
+	EnforcementAction EnforcementAction `json:"enforcementAction"`
+	// Message is the reason the object would be denied
+	//+optional
+	Message string `json:"message,omitempty"`
+	// Timestamp is the time the object was admitted
+	Timestamp metav1.Time `json:"timestamp"`
 }
 
 //+kubebuilder:object:root=true
@@ -44,6 +192,9 @@ type ProjectResourceQuotaStatus struct {
 //+kubebuilder:printcolumn:name="Namespaces",type="string",JSONPath=".spec.namespaces",description="Namespaces"
 //+kubebuilder:printcolumn:name="Hard",type="string",JSONPath=".spec.hard",description="Hard"
 //+kubebuilder:printcolumn:name="Used",type="string",JSONPath=".status.used",description="Used"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Ready"
+//+kubebuilder:printcolumn:name="OverQuota",type="string",JSONPath=".status.conditions[?(@.type==\"OverQuota\")].status",description="OverQuota"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ProjectResourceQuota is the Schema for the projectresourcequotas API
 type ProjectResourceQuota struct {
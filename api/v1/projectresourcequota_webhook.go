@@ -21,56 +21,14 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-)
-
-var resourceNameList = []corev1.ResourceName{
-	corev1.ResourceCPU,
-	corev1.ResourceMemory,
-	corev1.ResourceStorage,
-	corev1.ResourceEphemeralStorage,
-	corev1.ResourcePods,
-	corev1.ResourceServices,
-	corev1.ResourceReplicationControllers,
-	corev1.ResourceQuotas,
-	corev1.ResourceSecrets,
-	corev1.ResourceConfigMaps,
-	corev1.ResourcePersistentVolumeClaims,
-	corev1.ResourceServicesNodePorts,
-	corev1.ResourceServicesLoadBalancers,
-	corev1.ResourceRequestsCPU,
-	corev1.ResourceRequestsMemory,
-	corev1.ResourceRequestsStorage,
-	corev1.ResourceRequestsEphemeralStorage,
-	corev1.ResourceLimitsCPU,
-	corev1.ResourceLimitsMemory,
-	corev1.ResourceLimitsEphemeralStorage,
-}
 
-var resourceNameMap = map[corev1.ResourceName]struct{}{
-	corev1.ResourceCPU:                      {},
-	corev1.ResourceMemory:                   {},
-	corev1.ResourceStorage:                  {},
-	corev1.ResourceEphemeralStorage:         {},
-	corev1.ResourcePods:                     {},
-	corev1.ResourceServices:                 {},
-	corev1.ResourceReplicationControllers:   {},
-	corev1.ResourceQuotas:                   {},
-	corev1.ResourceSecrets:                  {},
-	corev1.ResourceConfigMaps:               {},
-	corev1.ResourcePersistentVolumeClaims:   {},
-	corev1.ResourceServicesNodePorts:        {},
-	corev1.ResourceServicesLoadBalancers:    {},
-	corev1.ResourceRequestsCPU:              {},
-	corev1.ResourceRequestsMemory:           {},
-	corev1.ResourceRequestsStorage:          {},
-	corev1.ResourceRequestsEphemeralStorage: {},
-	corev1.ResourceLimitsCPU:                {},
-	corev1.ResourceLimitsMemory:             {},
-	corev1.ResourceLimitsEphemeralStorage:   {},
-}
+	"github.com/jenting/projectresourcequota/internal/quota"
+)
 
 func SetupProjectResourceQuotaWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
@@ -109,23 +67,78 @@ type projectResourceQuotaValidator struct {
 	client.Client
 }
 
-// validateNamespace validates the spec.namespaces is not in other CRs
-func (v *projectResourceQuotaValidator) validateNamespace(ctx context.Context, prqName string, prqNamespaces []string) error {
+// validateNamespace validates the namespace selector of the project,
+// a namespace might belong to several projects, every project is enforced.
+// The project without namespaces tracks the namespaces of its descendant projects only.
+func (v *projectResourceQuotaValidator) validateNamespace(ctx context.Context, prq *ProjectResourceQuota) error {
+	if prq.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(prq.Spec.NamespaceSelector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateParent validates the parent project exists without a cycle,
+// and the hard limits of the children do not oversubscribe the hard limits of the parent.
+func (v *projectResourceQuotaValidator) validateParent(ctx context.Context, prq *ProjectResourceQuota) error {
 	prqList := &ProjectResourceQuotaList{}
 	if err := v.Client.List(ctx, prqList); err != nil {
 		return err
 	}
 
-	for _, prq := range prqList.Items {
-		// validate the other projectresourcequota CRs only
-		if prqName != prq.Name {
-			for _, namespace := range prq.Spec.Namespaces {
-				for _, prqNamepsace := range prqNamespaces {
-					if namespace == prqNamepsace {
-						return fmt.Errorf("namespace %s is already in project %s", prqNamepsace, prq.Name)
-					}
-				}
+	// replace the stored projectresourcequota CR with the one being validated
+	var prqs []ProjectResourceQuota
+	for _, other := range prqList.Items {
+		if other.Name != prq.Name {
+			prqs = append(prqs, other)
+		}
+	}
+	prqs = append(prqs, *prq)
+
+	if len(prq.Spec.Parent) > 0 {
+		if prq.Spec.Parent == prq.Name {
+			return fmt.Errorf("project %s cannot be its own parent", prq.Name)
+		}
+
+		var parent *ProjectResourceQuota
+		for i := range prqs {
+			if prqs[i].Name == prq.Spec.Parent {
+				parent = &prqs[i]
+			}
+		}
+		if parent == nil {
+			return fmt.Errorf("parent project %s is not found", prq.Spec.Parent)
+		}
+		for _, ancestor := range parent.Ancestors(prqs) {
+			if ancestor.Name == prq.Name {
+				return fmt.Errorf("parent project %s is a descendant of project %s", prq.Spec.Parent, prq.Name)
+			}
+		}
+
+		if err := validateChildrenHard(parent, prqs); err != nil {
+			return err
+		}
+	}
+
+	// the hard limits of the project might be lowered below the sum of the children
+	return validateChildrenHard(prq, prqs)
+}
+
+// validateChildrenHard validates the sum of the hard limits of the children is not greater than the hard limits of the parent
+func validateChildrenHard(parent *ProjectResourceQuota, prqs []ProjectResourceQuota) error {
+	for resourceName, hard := range parent.Spec.Hard {
+		var total resource.Quantity
+		for _, child := range prqs {
+			if child.Spec.Parent != parent.Name {
+				continue
 			}
+			if quantity, found := child.Spec.Hard[resourceName]; found {
+				total.Add(quantity)
+			}
+		}
+		if total.Cmp(hard) == 1 {
+			return fmt.Errorf("children hard limit %s %s exceeds parent project %s hard limit %s", resourceName, total.String(), parent.Name, hard.String())
 		}
 	}
 	return nil
@@ -134,13 +147,42 @@ func (v *projectResourceQuotaValidator) validateNamespace(ctx context.Context, p
 // validateResourceName validates the given resource name is supported
 func (v *projectResourceQuotaValidator) validateResourceName(ctx context.Context, rl corev1.ResourceList) error {
 	for resourceName := range rl {
-		if _, found := resourceNameMap[resourceName]; !found {
+		// the <storage-class-name>.storageclass.storage.k8s.io/<resource-name> resources
+		if quota.IsStorageClassResource(resourceName) {
+			continue
+		}
+		// the count/<resource>.<group> resources
+		if quota.IsObjectCountResource(resourceName) {
+			if _, ok := quota.ObjectCountGroupResource(resourceName); !ok {
+				return fmt.Errorf("resource name %s is not a valid count/<resource>.<group> resource name", resourceName)
+			}
+			continue
+		}
+		// the requests.<extended-resource-name> resources
+		if quota.IsExtendedResourceRequests(resourceName) {
+			continue
+		}
+		if !quota.IsStandardResource(resourceName) {
 			return fmt.Errorf("resource name %s is not supported", resourceName)
 		}
 	}
 	return nil
 }
 
+// validateSoft validates the soft limits are set in spec.hard and not greater than the hard limits
+func validateSoft(prq *ProjectResourceQuota) error {
+	for resourceName, soft := range prq.Spec.Soft {
+		hard, found := prq.Spec.Hard[resourceName]
+		if !found {
+			return fmt.Errorf("soft limit %s is not set in the hard limits", resourceName)
+		}
+		if soft.Cmp(hard) == 1 {
+			return fmt.Errorf("soft limit %s %s is greater than hard limit %s", resourceName, soft.String(), hard.String())
+		}
+	}
+	return nil
+}
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (v *projectResourceQuotaValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
 	prq, ok := obj.(*ProjectResourceQuota)
@@ -148,13 +190,28 @@ func (v *projectResourceQuotaValidator) ValidateCreate(ctx context.Context, obj
 		return fmt.Errorf("expected a ProjectResourceQuota but got a %T", obj)
 	}
 
-	// validate the spec.namespaces is not in other CRs
-	if err := v.validateNamespace(ctx, prq.Name, prq.Spec.Namespaces); err != nil {
+	// validate the namespace selector of the project
+	if err := v.validateNamespace(ctx, prq); err != nil {
+		return err
+	}
+
+	// validate the parent project and the hard limits of the children
+	if err := v.validateParent(ctx, prq); err != nil {
 		return err
 	}
 
 	// validate the given resource name is supported
-	return v.validateResourceName(ctx, prq.Spec.Hard)
+	if err := v.validateResourceName(ctx, prq.Spec.Hard); err != nil {
+		return err
+	}
+
+	// validate the soft limits against the hard limits
+	if err := validateSoft(prq); err != nil {
+		return err
+	}
+
+	// validate the scopes and the resource names allowed with them
+	return quota.ValidateScopes(prq.Spec.Scopes, prq.Spec.ScopeSelector, prq.Spec.Hard)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -164,8 +221,18 @@ func (v *projectResourceQuotaValidator) ValidateUpdate(ctx context.Context, oldO
 		return fmt.Errorf("expected a ProjectResourceQuota but got a %T", newObj)
 	}
 
-	// validate the spec.namespaces is not in other CRs
-	if err := v.validateNamespace(ctx, prq.Name, prq.Spec.Namespaces); err != nil {
+	// the projectresourcequota under deletion is updated to remove its finalizer
+	if prq.DeletionTimestamp != nil {
+		return nil
+	}
+
+	// validate the namespace selector of the project
+	if err := v.validateNamespace(ctx, prq); err != nil {
+		return err
+	}
+
+	// validate the parent project and the hard limits of the children
+	if err := v.validateParent(ctx, prq); err != nil {
 		return err
 	}
 
@@ -174,12 +241,34 @@ func (v *projectResourceQuotaValidator) ValidateUpdate(ctx context.Context, oldO
 		return err
 	}
 
-	// validates the spec.hard is not less than status.used
-	for _, resourceName := range resourceNameList {
-		hard := prq.Spec.Hard[resourceName]
+	// validate the soft limits against the hard limits
+	if err := validateSoft(prq); err != nil {
+		return err
+	}
+
+	// validate the scopes and the resource names allowed with them
+	if err := quota.ValidateScopes(prq.Spec.Scopes, prq.Spec.ScopeSelector, prq.Spec.Hard); err != nil {
+		return err
+	}
+
+	// the warn and dryrun enforcement actions trial the spec.hard, which might be less than status.used
+	if prq.GetEnforcementAction() != EnforcementActionDeny {
+		return nil
+	}
+	oldPrq, ok := oldObj.(*ProjectResourceQuota)
+	if !ok {
+		return fmt.Errorf("expected a ProjectResourceQuota but got a %T", oldObj)
+	}
+
+	// validates the decreased spec.hard is not less than status.used,
+	// the spec.hard less than status.used already, e.g. after the trial, does not block the other updates
+	for resourceName, hard := range prq.Spec.Hard {
+		if oldHard, found := oldPrq.Spec.Hard[resourceName]; found && hard.Cmp(oldHard) >= 0 {
+			continue
+		}
 		used := prq.Status.Used[resourceName]
 		if hard.Cmp(used) == -1 {
-			return fmt.Errorf("hard limit %s is less than used %s", hard.String(), used.String())
+			return fmt.Errorf("%s hard limit %s is less than used %s", resourceName, hard.String(), used.String())
 		}
 	}
 	return nil
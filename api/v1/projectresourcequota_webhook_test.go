@@ -0,0 +1,166 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// warningRecorder records the warnings returned by the API server
+type warningRecorder struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (r *warningRecorder) HandleWarningHeader(code int, agent string, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, text)
+}
+
+var _ = Describe("ProjectResourceQuota", func() {
+	It("should not admit ConfigMaps over the hard limit of the parent project", func() {
+		name := "projectresourcequota-parent"
+		parent := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("3")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, parent)).To(Succeed())
+
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name + "-team"}})).To(Succeed())
+		team := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-team"},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name + "-team"},
+				Parent:     parent.Name,
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("2")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, team)).To(Succeed())
+
+		// the children hard limits cannot oversubscribe the parent hard limits
+		oversubscribed := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-oversubscribed"},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name + "-oversubscribed"},
+				Parent:     parent.Name,
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("2")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, oversubscribed)).NotTo(Succeed())
+
+		// the child project without spec.hard.configmaps is limited by the parent project only
+		other := createProject(name+"-other", corev1.ResourceList{
+			corev1.ResourceSecrets: resource.MustParse("1"),
+		}, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name + "-other"}})
+		other.Spec.Parent = parent.Name
+		Expect(k8sClient.Update(ctx, other)).To(Succeed())
+		Eventually(func() bool {
+			probe := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name + "-other"}}
+			if err := k8sClient.Create(ctx, probe, client.DryRunAll); err != nil {
+				return false
+			}
+			return IsAttributedTo(probe, parent.Name)
+		}).Should(BeTrue())
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name + "-other"}}
+		})
+		Expect(admitted).To(Equal(3))
+
+		// the parent project is full
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: name + "-team"}}
+		Expect(k8sClient.Create(ctx, cm)).NotTo(Succeed())
+	})
+
+	It("should admit ConfigMaps over the soft limit with a warning", func() {
+		name := "projectresourcequota-soft"
+		prq := createProject(name, corev1.ResourceList{
+			corev1.ResourceConfigMaps: resource.MustParse("2"),
+		}, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name}})
+
+		// the soft limit must not be greater than the hard limit
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		prq.Spec.Soft = corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("3")}
+		Expect(k8sClient.Update(ctx, prq)).NotTo(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		prq.Spec.Soft = corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")}
+		Expect(k8sClient.Update(ctx, prq)).To(Succeed())
+
+		recorder := &warningRecorder{}
+		warningCfg := rest.CopyConfig(cfg)
+		warningCfg.WarningHandler = recorder
+		warningClient, err := client.New(warningCfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+
+		// within the soft limit
+		Expect(warningClient.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-0", Namespace: name}})).To(Succeed())
+		Expect(recorder.warnings).To(BeEmpty())
+
+		// over the soft limit, within the hard limit
+		Expect(warningClient.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-1", Namespace: name}})).To(Succeed())
+		Expect(recorder.warnings).To(ContainElement(ContainSubstring("soft limit")))
+
+		// over the hard limit
+		Expect(warningClient.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-2", Namespace: name}})).NotTo(Succeed())
+	})
+
+	It("should admit ConfigMaps over the hard limit with the dryrun enforcement action", func() {
+		name := "projectresourcequota-dryrun"
+		prq := createProject(name, corev1.ResourceList{
+			corev1.ResourceConfigMaps: resource.MustParse("2"),
+		}, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name}})
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		prq.Spec.EnforcementAction = EnforcementActionDryRun
+		Expect(k8sClient.Update(ctx, prq)).To(Succeed())
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+		})
+		Expect(admitted).To(Equal(concurrency))
+
+		// the would-be denials are recorded in the status
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		Expect(prq.Status.Violations).To(HaveLen(MaxViolations))
+		for _, violation := range prq.Status.Violations {
+			Expect(violation.ResourceName).To(Equal(corev1.ResourceConfigMaps))
+			Expect(violation.EnforcementAction).To(Equal(EnforcementActionDryRun))
+		}
+
+		// the deny enforcement action blocks again
+		prq.Spec.EnforcementAction = EnforcementActionDeny
+		Expect(k8sClient.Update(ctx, prq)).To(Succeed())
+		Eventually(func() error {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: name}}
+			return k8sClient.Create(ctx, cm, client.DryRunAll)
+		}).ShouldNot(Succeed())
+	})
+})
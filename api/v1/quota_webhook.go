@@ -0,0 +1,222 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/jenting/projectresourcequota/internal/quota"
+)
+
+// SetupQuotaWebhookWithManager registers the webhooks of the kinds tracked by the quota evaluators.
+// A new kind is tracked by registering its quota evaluator and adding its resource to the webhook markers below.
+func SetupQuotaWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	mgr.GetWebhookServer().Register("/mutate-quota", &webhook.Admission{
+		Handler: &quotaAnnotator{mgr.GetClient(), decoder},
+	})
+	mgr.GetWebhookServer().Register("/validate-quota", &webhook.Admission{
+		Handler: &quotaValidator{mgr.GetClient(), decoder, newQuotaReserver(mgr)},
+	})
+	return nil
+}
+
+//+kubebuilder:webhook:path=/mutate-quota,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",matchPolicy=Exact,resources=configmaps;persistentvolumeclaims;pods;replicationcontrollers;resourcequotas;secrets;services,verbs=create;update,versions=v1,name=quota.jenting.io,admissionReviewVersions=v1
+
+// quotaAnnotator annotates the objects tracked by the quota evaluators
+type quotaAnnotator struct {
+	client.Client
+	decoder *admission.Decoder
+}
+
+func (a *quotaAnnotator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	log := logf.FromContext(ctx)
+	evaluator, found := quota.EvaluatorFor(req.Kind.Kind)
+	if !found {
+		return admission.Allowed("")
+	}
+
+	obj := evaluator.NewObject()
+	if err := a.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// attribute to the projectresourcequotas.jenting.io CRs the namespace belongs to which have the resources tracked by the kind set,
+	// and drop the ones the namespace no longer belongs to, e.g. the namespace moved to another project, so the usage follows the namespace
+	prqs, err := GetProjectResourceQuotas(ctx, a.Client, req.Namespace)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	prqNames, err := AttributableProjectResourceQuotas(evaluator, obj, prqs)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	attributed := sets.NewString(ProjectResourceQuotaNames(obj)...)
+	if attributed.Equal(prqNames) {
+		return admission.Allowed("")
+	}
+	if err := SetAttribution(obj, prqNames.List()); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	log.Info("Object annotated", "kind", evaluator.Kind(), "prqNames", prqNames.List(), "staleNames", attributed.Difference(prqNames).List())
+
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+//+kubebuilder:webhook:path=/validate-quota,mutating=false,failurePolicy=ignore,sideEffects=NoneOnDryRun,groups="",matchPolicy=Exact,resources=configmaps;persistentvolumeclaims;pods;pods/resize;replicationcontrollers;resourcequotas;secrets;services,verbs=create;update,versions=v1,name=quota.jenting.io,admissionReviewVersions=v1
+
+// quotaValidator validates the objects tracked by the quota evaluators
+type quotaValidator struct {
+	client.Client
+	decoder  *admission.Decoder
+	reserver *quotaReserver
+}
+
+func (v *quotaValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	log := logf.FromContext(ctx)
+	evaluator, found := quota.EvaluatorFor(req.Kind.Kind)
+	if !found {
+		return admission.Allowed("")
+	}
+
+	obj := evaluator.NewObject()
+	if err := v.decoder.DecodeRaw(req.Object, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// the object in the request might omit the namespace
+	obj.SetNamespace(req.Namespace)
+
+	if req.Operation == admissionv1.Update {
+		// the object under deletion is not charged, otherwise removing its finalizers, e.g. kubernetes.io/pvc-protection, is denied
+		if obj.GetDeletionTimestamp() != nil {
+			return admission.Allowed("")
+		}
+		// the terminal pod is not charged
+		if pod, ok := obj.(*corev1.Pod); ok && !quota.QuotaV1Pod(pod, time.Now()) {
+			return admission.Allowed("")
+		}
+	}
+
+	// derive the projectresourcequotas.jenting.io CRs from the namespace rather than trusting the annotation,
+	// the annotation forged or stripped by the users is rejected so the objects cannot escape the project usage
+	prqs, err := GetProjectResourceQuotas(ctx, v.Client, req.Namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	expected, err := AttributableProjectResourceQuotas(evaluator, obj, prqs)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateAttribution(obj, expected); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if expected.Len() == 0 {
+		return admission.Allowed("")
+	}
+	prqNames := expected.List()
+
+	usage := evaluator.Usage(obj)
+	usages := map[string]corev1.ResourceList{}
+	switch req.Operation {
+	case admissionv1.Create:
+		log.Info("Validating object creation", "kind", evaluator.Kind())
+		for _, prqName := range prqNames {
+			usages[prqName] = usage
+		}
+	case admissionv1.Update:
+		log.Info("Validating object update", "kind", evaluator.Kind(), "subResource", req.SubResource)
+		oldObj := evaluator.NewObject()
+		if err := v.decoder.DecodeRaw(req.OldObject, oldObj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		// the object attributed to the project already charges the increased usage only,
+		// e.g. the in-place pod resize, the volume expansion or the service type change
+		delta := quota.Delta(usage, evaluator.Usage(oldObj))
+		// the object adopted by the controller, e.g. created before the project or moved with its namespace,
+		// is not denied, the controller counts it and reports the OverQuota condition instead
+		adopted := isControllerAttribution(req)
+		for _, prqName := range prqNames {
+			// the object attributed to the project by the user update charges its whole usage
+			if !IsAttributedTo(oldObj, prqName) && !adopted {
+				usages[prqName] = usage
+				continue
+			}
+			if len(delta) > 0 {
+				usages[prqName] = delta
+			}
+		}
+		if len(usages) == 0 {
+			return admission.Allowed("")
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	// reserve the usage in the projectresourcequotas.jenting.io CRs
+	ctx = admission.NewContextWithRequest(ctx, req)
+	return validationResponse(v.reserver.reserveUsages(ctx, usages, evaluator.Kind(), obj))
+}
+
+// isControllerAttribution returns whether the update is the attribution patch of the controller identified by its field manager
+func isControllerAttribution(req admission.Request) bool {
+	if len(req.Options.Raw) == 0 {
+		return false
+	}
+	options := &metav1.PatchOptions{}
+	if err := json.Unmarshal(req.Options.Raw, options); err != nil {
+		return false
+	}
+	return options.FieldManager == ControllerFieldManager
+}
+
+// validateAttribution validates the project-resource-quota annotation of the object lists the expected ProjectResourceQuotas
+func validateAttribution(obj client.Object, expected sets.String) error {
+	attributed := sets.NewString(ProjectResourceQuotaNames(obj)...)
+	if attributed.Equal(expected) {
+		return nil
+	}
+	if attributed.Len() == 0 {
+		return fmt.Errorf("annotation %s cannot be removed, the object is attributed to project resource quotas %s", ProjectResourceQuotaAnnotation, strings.Join(expected.List(), ","))
+	}
+	return fmt.Errorf("annotation %s value %s does not match project resource quotas %s of namespace %s",
+		ProjectResourceQuotaAnnotation, strings.Join(attributed.List(), ","), strings.Join(expected.List(), ","), obj.GetNamespace())
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Quota", func() {
+	It("should deny the update increasing the usage over the hard limit", func() {
+		name := "quota-update"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+		prq := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard: corev1.ResourceList{
+					corev1.ResourceServices:              resource.MustParse("1"),
+					corev1.ResourceServicesLoadBalancers: resource.MustParse("0"),
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: name},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeClusterIP,
+				Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		}
+		Eventually(func() bool {
+			obj := svc.DeepCopy()
+			if err := k8sClient.Create(ctx, obj, client.DryRunAll); err != nil {
+				return false
+			}
+			return IsAttributedTo(obj, prq.Name)
+		}).Should(BeTrue())
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		// the unchanged usage is admitted although services is at the hard limit
+		svc.Labels = map[string]string{"app": name}
+		Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+
+		// the load balancer is over the hard limit
+		svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+		Expect(k8sClient.Update(ctx, svc)).NotTo(Succeed())
+	})
+
+	It("should admit the adoption of the existing object into a full project", func() {
+		name := "quota-adoption"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+
+		// the object created before the project
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: name}}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+		prq := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("0")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		// the user update attributing the existing object is charged its whole usage
+		Eventually(func() error {
+			latest := &corev1.ConfigMap{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(cm), latest); err != nil {
+				return err
+			}
+			latest.Labels = map[string]string{"app": name}
+			return k8sClient.Update(ctx, latest)
+		}).ShouldNot(Succeed())
+
+		// the controller attributes the existing object although the project is full
+		Eventually(func() error {
+			latest := &corev1.ConfigMap{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(cm), latest); err != nil {
+				return err
+			}
+			base := latest.DeepCopy()
+			if err := AttributeTo(latest, prq.Name); err != nil {
+				return err
+			}
+			return k8sClient.Patch(ctx, latest, client.MergeFrom(base), client.FieldOwner(ControllerFieldManager))
+		}).Should(Succeed())
+
+		// the new object is denied
+		Expect(k8sClient.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name + "-new", Namespace: name}})).NotTo(Succeed())
+	})
+
+	It("should not let the users forge or strip the annotation", func() {
+		name := "quota-attribution"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+		prq := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceSecrets: resource.MustParse("10")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		// the forged annotation is replaced by the projects of the namespace
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: name}}
+		Expect(AddAnnotation(secret, ProjectResourceQuotaAnnotation, "other-project")).To(Succeed())
+		Eventually(func() []string {
+			obj := secret.DeepCopy()
+			if err := k8sClient.Create(ctx, obj, client.DryRunAll); err != nil {
+				return nil
+			}
+			return ProjectResourceQuotaNames(obj)
+		}).Should(Equal([]string{prq.Name}))
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		// the stripped annotation is restored
+		Expect(RemoveAnnotation(secret, ProjectResourceQuotaAnnotation)).To(Succeed())
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+		Expect(ProjectResourceQuotaNames(secret)).To(Equal([]string{prq.Name}))
+
+		// the annotation disagreeing with the namespace is rejected when it is not corrected
+		expected := sets.NewString(prq.Name)
+		Expect(validateAttribution(secret, expected)).To(Succeed())
+		Expect(RemoveAnnotation(secret, ProjectResourceQuotaAnnotation)).To(Succeed())
+		Expect(validateAttribution(secret, expected)).NotTo(Succeed())
+		Expect(AttributeTo(secret, "other-project")).To(Succeed())
+		Expect(validateAttribution(secret, expected)).NotTo(Succeed())
+	})
+
+	It("should not admit Pods matching the scopes over the hard limit", func() {
+		name := "quota-scopes"
+		newPod := func(podName string, requests corev1.ResourceList) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: name},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:      "nginx",
+						Image:     "nginx",
+						Resources: corev1.ResourceRequirements{Requests: requests},
+					}},
+				},
+			}
+		}
+		burstable := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}
+
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+		prq := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourcePods: resource.MustParse("3")},
+				Scopes:     []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+		Eventually(func() bool {
+			probe := newPod("probe", burstable)
+			if err := k8sClient.Create(ctx, probe, client.DryRunAll); err != nil {
+				return false
+			}
+			return IsAnnotationExists(probe, ProjectResourceQuotaAnnotation)
+		}).Should(BeTrue())
+
+		// the BestEffort pods are not tracked
+		admitted := createConcurrently(func(i int) client.Object {
+			return newPod(fmt.Sprintf("besteffort-%d", i), nil)
+		})
+		Expect(admitted).To(Equal(concurrency))
+
+		admitted = createConcurrently(func(i int) client.Object {
+			return newPod(fmt.Sprintf("burstable-%d", i), burstable)
+		})
+		Expect(admitted).To(Equal(3))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		used := prq.Status.Used[corev1.ResourcePods]
+		Expect(used.Value()).To(BeEquivalentTo(3))
+	})
+
+	It("should not admit ConfigMaps over the hard limit of any project the namespace belongs to", func() {
+		name := "quota-layered"
+		department := createProject(name, corev1.ResourceList{
+			corev1.ResourceConfigMaps: resource.MustParse("10"),
+		}, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name}})
+
+		team := &ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-team"},
+			Spec: ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("3")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, team)).To(Succeed())
+		Eventually(func() bool {
+			probe := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name}}
+			if err := k8sClient.Create(ctx, probe, client.DryRunAll); err != nil {
+				return false
+			}
+			return IsAttributedTo(probe, department.Name) && IsAttributedTo(probe, team.Name)
+		}).Should(BeTrue())
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+		})
+		Expect(admitted).To(Equal(3))
+
+		for _, prq := range []*ProjectResourceQuota{department, team} {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+			used := prq.Status.Used[corev1.ResourceConfigMaps]
+			Expect(used.Value()).To(BeEquivalentTo(3))
+			Expect(prq.Status.Reservations).To(HaveLen(3))
+		}
+	})
+})
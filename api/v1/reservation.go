@@ -0,0 +1,323 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/jenting/projectresourcequota/internal/metrics"
+)
+
+// ReservationTimeout is how long a reservation is kept in status.reservations
+// when the controller does not observe the admitted object, e.g. the object creation failed after admission.
+const ReservationTimeout = time.Minute
+
+// projectLocks serializes the admission decisions per project within the webhook server.
+// The optimistic concurrency of the status update serializes them across the webhook server replicas.
+var projectLocks sync.Map
+
+func lockProject(prqName string) func() {
+	mu, _ := projectLocks.LoadOrStore(prqName, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// quotaReserver reserves the usage of the admitted objects in the ProjectResourceQuota status
+type quotaReserver struct {
+	client client.Client
+	// reader reads the ProjectResourceQuota from the API server directly rather than the cache,
+	// otherwise the reservations made by the other admission requests are not visible yet.
+	reader client.Reader
+	// recorder records the denied admission requests as events on the ProjectResourceQuota
+	recorder record.EventRecorder
+}
+
+func newQuotaReserver(mgr ctrl.Manager) *quotaReserver {
+	return &quotaReserver{
+		client:   mgr.GetClient(),
+		reader:   mgr.GetAPIReader(),
+		recorder: mgr.GetEventRecorderFor("projectresourcequota-webhook"),
+	}
+}
+
+// reserve charges the usage of the admitted object to the status.used of every ProjectResourceQuota the object is attributed to,
+// and records a reservation until the controller counts the object.
+// It returns an error if status.used + usage > spec.hard of any ProjectResourceQuota with the deny enforcement action,
+// and the warnings if status.used + usage > spec.soft, or > spec.hard with the warn enforcement action.
+func (r *quotaReserver) reserve(ctx context.Context, prqNames []string, kind string, obj client.Object, usage corev1.ResourceList) (Warnings, error) {
+	usages := map[string]corev1.ResourceList{}
+	for _, prqName := range prqNames {
+		usages[prqName] = usage
+	}
+	return r.reserveUsages(ctx, usages, kind, obj)
+}
+
+// reserveUsages charges the usage per ProjectResourceQuota name of the admitted object like reserve,
+// e.g. the updated object charges the increased usage to the projects it is attributed to already,
+// and its whole usage to the projects the user update attributes it to newly.
+func (r *quotaReserver) reserveUsages(ctx context.Context, usages map[string]corev1.ResourceList, kind string, obj client.Object) (Warnings, error) {
+	// lock the projects in order, otherwise the concurrent admission requests might deadlock
+	names := sets.StringKeySet(usages).List()
+	for _, prqName := range names {
+		unlock := lockProject(prqName)
+		defer unlock()
+	}
+
+	var warnings Warnings
+	var reserved []string
+	for _, prqName := range names {
+		projectWarnings, err := r.reserveProject(ctx, prqName, kind, obj, usages[prqName])
+		if err != nil {
+			// the object is denied, release the usage reserved in the other projects
+			for _, name := range reserved {
+				if releaseErr := r.release(ctx, name, obj, usages[name]); releaseErr != nil {
+					logf.FromContext(ctx).Error(releaseErr, "failed to release the reservation", "prqName", name)
+				}
+			}
+			return nil, err
+		}
+		reserved = append(reserved, prqName)
+		warnings = append(warnings, projectWarnings...)
+	}
+	return warnings, nil
+}
+
+// reserveProject charges the usage of the admitted object to the ProjectResourceQuota status.used,
+// the caller must hold the project lock.
+func (r *quotaReserver) reserveProject(ctx context.Context, prqName, kind string, obj client.Object, usage corev1.ResourceList) (Warnings, error) {
+	var warnings Warnings
+	var violation *ProjectResourceQuotaViolation
+	var prq *ProjectResourceQuota
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		warnings = nil
+		violation = nil
+
+		// get the current projectresourcequotas.jenting.io CR
+		prq = &ProjectResourceQuota{}
+		if err := r.reader.Get(ctx, types.NamespacedName{Name: prqName}, prq); err != nil {
+			return err
+		}
+
+		// the dry-run request does not persist the object, nothing to reserve
+		if isDryRun(ctx) {
+			warnings = checkSoftUsage(prq, usage)
+			if _, err := checkUsage(prq, usage); err != nil {
+				switch prq.GetEnforcementAction() {
+				case EnforcementActionWarn:
+					warnings = append(warnings, err.Error())
+				case EnforcementActionDeny:
+					return err
+				}
+			}
+			return nil
+		}
+
+		// the object is reserved already, e.g. the admission request is retried
+		for _, reservation := range prq.Status.Reservations {
+			if isReservationOf(reservation, obj, usage) {
+				return nil
+			}
+		}
+
+		// the status.used + usage > spec.soft is admitted with the warnings
+		warnings = checkSoftUsage(prq, usage)
+
+		// check the status.used + usage is not greater than spec.hard
+		if resourceName, err := checkUsage(prq, usage); err != nil {
+			enforcementAction := prq.GetEnforcementAction()
+			if enforcementAction == EnforcementActionDeny {
+				r.recordDenial(prq, kind, obj, resourceName, err)
+				return err
+			}
+
+			// the warn and dryrun enforcement actions admit the object and record the violation
+			if enforcementAction == EnforcementActionWarn {
+				warnings = append(warnings, err.Error())
+			}
+			violation = &ProjectResourceQuotaViolation{
+				Kind:              kind,
+				Namespace:         obj.GetNamespace(),
+				Name:              obj.GetName(),
+				ResourceName:      resourceName,
+				EnforcementAction: enforcementAction,
+				Message:           err.Error(),
+				Timestamp:         metav1.Now(),
+			}
+			prq.Status.Violations = append(prq.Status.Violations, *violation)
+			if len(prq.Status.Violations) > MaxViolations {
+				prq.Status.Violations = prq.Status.Violations[len(prq.Status.Violations)-MaxViolations:]
+			}
+		}
+
+		reserved := corev1.ResourceList{}
+		for resourceName, quantity := range usage {
+			if _, found := prq.Spec.Hard[resourceName]; found {
+				reserved[resourceName] = quantity.DeepCopy()
+			}
+		}
+		if len(reserved) == 0 {
+			return nil
+		}
+
+		if prq.Status.Used == nil {
+			prq.Status.Used = make(corev1.ResourceList)
+		}
+		for resourceName, quantity := range reserved {
+			used := prq.Status.Used[resourceName]
+			used.Add(quantity)
+			prq.Status.Used[resourceName] = used
+		}
+		prq.Status.Reservations = append(prq.Status.Reservations, ProjectResourceQuotaReservation{
+			UID:               obj.GetUID(),
+			Kind:              kind,
+			Namespace:         obj.GetNamespace(),
+			Name:              obj.GetName(),
+			ResourceVersion:   obj.GetResourceVersion(),
+			Usage:             reserved,
+			CreationTimestamp: metav1.Now(),
+		})
+		return r.client.Status().Update(ctx, prq)
+	})
+	if err == nil && violation != nil {
+		r.recordViolation(prq, violation)
+	}
+	return warnings, err
+}
+
+// release drops the reservation of the usage of the object from the ProjectResourceQuota and subtracts its usage from status.used,
+// the caller must hold the project lock.
+func (r *quotaReserver) release(ctx context.Context, prqName string, obj client.Object, usage corev1.ResourceList) error {
+	if isDryRun(ctx) {
+		return nil
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		prq := &ProjectResourceQuota{}
+		if err := r.reader.Get(ctx, types.NamespacedName{Name: prqName}, prq); err != nil {
+			return err
+		}
+
+		var reservations []ProjectResourceQuotaReservation
+		var released *ProjectResourceQuotaReservation
+		for i, reservation := range prq.Status.Reservations {
+			if released == nil && isReservationOf(reservation, obj, usage) {
+				released = &prq.Status.Reservations[i]
+				continue
+			}
+			reservations = append(reservations, reservation)
+		}
+		if released == nil {
+			return nil
+		}
+
+		for resourceName, quantity := range released.Usage {
+			if used, found := prq.Status.Used[resourceName]; found {
+				used.Sub(quantity)
+				prq.Status.Used[resourceName] = used
+			}
+		}
+		prq.Status.Reservations = reservations
+		return r.client.Status().Update(ctx, prq)
+	})
+}
+
+// isReservationOf returns true if the reservation is made for the usage of the object.
+// The object might be reserved by several admission webhooks, e.g. the count/<resource>.<group> and the quota webhooks,
+// so the reservation is identified by the reserved resource names besides the object UID and resource version.
+func isReservationOf(reservation ProjectResourceQuotaReservation, obj client.Object, usage corev1.ResourceList) bool {
+	if reservation.UID != obj.GetUID() || reservation.ResourceVersion != obj.GetResourceVersion() {
+		return false
+	}
+	for resourceName := range reservation.Usage {
+		if _, found := usage[resourceName]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// recordDenial counts the denied admission request in the metrics and records an event on the ProjectResourceQuota
+func (r *quotaReserver) recordDenial(prq *ProjectResourceQuota, kind string, obj client.Object, resourceName corev1.ResourceName, err error) {
+	metrics.RecordAdmissionDenied(prq.Name, resourceName, kind)
+	r.recorder.Eventf(prq, corev1.EventTypeWarning, "QuotaExceeded", "%s %s/%s is denied: %v", kind, obj.GetNamespace(), obj.GetName(), err)
+}
+
+// recordViolation counts the admitted violation in the metrics and records an event on the ProjectResourceQuota
+func (r *quotaReserver) recordViolation(prq *ProjectResourceQuota, violation *ProjectResourceQuotaViolation) {
+	metrics.RecordAdmissionViolation(prq.Name, violation.ResourceName, violation.Kind, string(violation.EnforcementAction))
+	r.recorder.Eventf(prq, corev1.EventTypeWarning, "QuotaViolated", "%s %s/%s is admitted by the %s enforcement action: %s",
+		violation.Kind, violation.Namespace, violation.Name, violation.EnforcementAction, violation.Message)
+}
+
+// checkUsage returns the resource name and an error if status.used + usage > spec.hard
+func checkUsage(prq *ProjectResourceQuota, usage corev1.ResourceList) (corev1.ResourceName, error) {
+	for resourceName, quantity := range usage {
+		hard, found := prq.Spec.Hard[resourceName]
+		if !found {
+			continue
+		}
+
+		used := prq.Status.Used[resourceName]
+		requested := quantity.DeepCopy()
+		requested.Add(used)
+		if requested.Cmp(hard) == 1 {
+			return resourceName, fmt.Errorf("over project resource quota. %s request %s + used %s > hard limit %s", resourceName, quantity.String(), used.String(), hard.String())
+		}
+	}
+	return "", nil
+}
+
+// checkSoftUsage returns the warnings if status.used + usage > spec.soft
+func checkSoftUsage(prq *ProjectResourceQuota, usage corev1.ResourceList) Warnings {
+	var warnings Warnings
+	for resourceName, quantity := range usage {
+		soft, found := prq.Spec.Soft[resourceName]
+		if !found {
+			continue
+		}
+
+		used := prq.Status.Used[resourceName]
+		requested := quantity.DeepCopy()
+		requested.Add(used)
+		if requested.Cmp(soft) == 1 {
+			warnings = append(warnings, fmt.Sprintf("over project resource quota %s soft limit. %s request %s + used %s > soft limit %s", prq.Name, resourceName, quantity.String(), used.String(), soft.String()))
+		}
+	}
+	return warnings
+}
+
+func isDryRun(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false
+	}
+	return req.DryRun != nil && *req.DryRun
+}
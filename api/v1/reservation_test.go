@@ -0,0 +1,177 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// concurrency is the number of objects created concurrently
+const concurrency = 50
+
+// createProject creates the namespace and the ProjectResourceQuota,
+// and waits until the webhook server annotates the objects in the namespace.
+func createProject(name string, hard corev1.ResourceList, probe client.Object) *ProjectResourceQuota {
+	Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+
+	prq := &ProjectResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: ProjectResourceQuotaSpec{
+			Namespaces: []string{name},
+			Hard:       hard,
+		},
+	}
+	Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+	Eventually(func() bool {
+		obj := probe.DeepCopyObject().(client.Object)
+		if err := k8sClient.Create(ctx, obj, client.DryRunAll); err != nil {
+			return false
+		}
+		return IsAnnotationExists(obj, ProjectResourceQuotaAnnotation)
+	}).Should(BeTrue())
+	return prq
+}
+
+// createConcurrently creates the objects concurrently and returns the number of admitted objects
+func createConcurrently(newObject func(i int) client.Object) int {
+	var admitted int32
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			if err := k8sClient.Create(ctx, newObject(i)); err == nil {
+				atomic.AddInt32(&admitted, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return int(admitted)
+}
+
+var _ = Describe("Reservation", func() {
+	It("should not admit ConfigMaps over the hard limit under concurrent creation", func() {
+		name := "reservation-configmap"
+		prq := createProject(name, corev1.ResourceList{
+			corev1.ResourceConfigMaps: resource.MustParse("10"),
+		}, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name}})
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+		})
+		Expect(admitted).To(Equal(10))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		used := prq.Status.Used[corev1.ResourceConfigMaps]
+		Expect(used.Value()).To(BeEquivalentTo(10))
+		Expect(prq.Status.Reservations).To(HaveLen(10))
+	})
+
+	It("should not admit Pods over the requests.cpu hard limit under concurrent creation", func() {
+		name := "reservation-pod"
+		newPod := func(podName string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: name},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "nginx",
+						Image: "nginx",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+						},
+					}},
+				},
+			}
+		}
+		prq := createProject(name, corev1.ResourceList{
+			corev1.ResourcePods:        resource.MustParse("50"),
+			corev1.ResourceRequestsCPU: resource.MustParse("1"),
+		}, newPod("probe"))
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return newPod(fmt.Sprintf("pod-%d", i))
+		})
+		Expect(admitted).To(Equal(10))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		used := prq.Status.Used[corev1.ResourceRequestsCPU]
+		Expect(used.Cmp(resource.MustParse("1"))).To(Equal(0))
+		used = prq.Status.Used[corev1.ResourcePods]
+		Expect(used.Value()).To(BeEquivalentTo(10))
+	})
+
+	It("should not admit objects over the count/<resource> hard limit under concurrent creation", func() {
+		name := "reservation-objectcount"
+		prq := createProject(name, corev1.ResourceList{
+			corev1.ResourceConfigMaps: resource.MustParse("50"),
+			"count/configmaps":        resource.MustParse("5"),
+		}, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: name}})
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+		})
+		Expect(admitted).To(Equal(5))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		used := prq.Status.Used["count/configmaps"]
+		Expect(used.Value()).To(BeEquivalentTo(5))
+	})
+
+	It("should not admit Pods over the extended resource hard limit under concurrent creation", func() {
+		name := "reservation-extended"
+		newPod := func(podName string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: name},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "nginx",
+						Image: "nginx",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{"example.com/foo": resource.MustParse("1")},
+							Limits:   corev1.ResourceList{"example.com/foo": resource.MustParse("1")},
+						},
+					}},
+				},
+			}
+		}
+		prq := createProject(name, corev1.ResourceList{
+			"requests.example.com/foo": resource.MustParse("3"),
+		}, newPod("probe"))
+
+		admitted := createConcurrently(func(i int) client.Object {
+			return newPod(fmt.Sprintf("pod-%d", i))
+		})
+		Expect(admitted).To(Equal(3))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		used := prq.Status.Used["requests.example.com/foo"]
+		Expect(used.Value()).To(BeEquivalentTo(3))
+	})
+})
@@ -18,6 +18,8 @@ package v1
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -100,3 +102,66 @@ func RemoveAnnotation(obj runtime.Object, key string) error {
 
 	return nil
 }
+
+// ProjectResourceQuotaNames returns the names of the ProjectResourceQuotas the object is attributed to,
+// the project-resource-quota annotation value is the comma-separated ProjectResourceQuota names.
+func ProjectResourceQuotaNames(obj runtime.Object) []string {
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+
+	val, found := metadata.GetAnnotations()[ProjectResourceQuotaAnnotation]
+	if !found || len(val) == 0 {
+		return nil
+	}
+	return strings.Split(val, ",")
+}
+
+// IsAttributedTo returns whether the object is attributed to the ProjectResourceQuota
+func IsAttributedTo(obj runtime.Object, prqName string) bool {
+	for _, name := range ProjectResourceQuotaNames(obj) {
+		if name == prqName {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeTo attributes the object to the ProjectResourceQuota in addition to the other ProjectResourceQuotas
+func AttributeTo(obj runtime.Object, prqName string) error {
+	if IsAttributedTo(obj, prqName) {
+		return nil
+	}
+
+	names := append(ProjectResourceQuotaNames(obj), prqName)
+	sort.Strings(names)
+	return AddAnnotation(obj, ProjectResourceQuotaAnnotation, strings.Join(names, ","))
+}
+
+// UnattributeFrom removes the ProjectResourceQuota from the object attribution,
+// the annotation is removed once the object is not attributed to any ProjectResourceQuota.
+func UnattributeFrom(obj runtime.Object, prqName string) error {
+	var names []string
+	for _, name := range ProjectResourceQuotaNames(obj) {
+		if name != prqName {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return RemoveAnnotation(obj, ProjectResourceQuotaAnnotation)
+	}
+	return AddAnnotation(obj, ProjectResourceQuotaAnnotation, strings.Join(names, ","))
+}
+
+// SetAttribution attributes the object to the ProjectResourceQuotas only,
+// the annotation is removed if the object is not attributed to any ProjectResourceQuota.
+func SetAttribution(obj runtime.Object, prqNames []string) error {
+	if len(prqNames) == 0 {
+		return RemoveAnnotation(obj, ProjectResourceQuotaAnnotation)
+	}
+
+	names := append([]string{}, prqNames...)
+	sort.Strings(names)
+	return AddAnnotation(obj, ProjectResourceQuotaAnnotation, strings.Join(names, ","))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:object:generate=false
+
+// Warnings are the warning messages returned to the client with the admission response
+type Warnings []string
+
+// CustomValidator validates an operation and returns the warnings, like the controller-runtime admission.CustomValidator.
+// The vendored controller-runtime admission.CustomValidator cannot return the warnings yet.
+type CustomValidator interface {
+	ValidateCreate(ctx context.Context, obj runtime.Object) (Warnings, error)
+	ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (Warnings, error)
+	ValidateDelete(ctx context.Context, obj runtime.Object) (Warnings, error)
+}
+
+// registerValidator registers the validating webhook of the object type at the path
+func registerValidator(mgr ctrl.Manager, path string, obj runtime.Object, validator CustomValidator) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	mgr.GetWebhookServer().Register(path, &webhook.Admission{
+		Handler: &validatorForType{object: obj, validator: validator, decoder: decoder},
+	})
+	return nil
+}
+
+// validatorForType handles the admission requests of the object type with the CustomValidator
+type validatorForType struct {
+	object    runtime.Object
+	validator CustomValidator
+	decoder   *admission.Decoder
+}
+
+// Handle handles admission requests.
+func (h *validatorForType) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ctx = admission.NewContextWithRequest(ctx, req)
+
+	// get the object in the request
+	obj := h.object.DeepCopyObject()
+
+	var warnings Warnings
+	var err error
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := h.decoder.Decode(req, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		warnings, err = h.validator.ValidateCreate(ctx, obj)
+	case admissionv1.Update:
+		oldObj := obj.DeepCopyObject()
+		if err := h.decoder.DecodeRaw(req.Object, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := h.decoder.DecodeRaw(req.OldObject, oldObj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		warnings, err = h.validator.ValidateUpdate(ctx, oldObj, obj)
+	case admissionv1.Delete:
+		// the OldObject contains the object being deleted
+		if err := h.decoder.DecodeRaw(req.OldObject, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+
+		warnings, err = h.validator.ValidateDelete(ctx, obj)
+	default:
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("unknown operation request %q", req.Operation))
+	}
+
+	return validationResponse(warnings, err)
+}
+
+// validationResponse returns the admission response of the validation result with the warnings
+func validationResponse(warnings Warnings, err error) admission.Response {
+	var resp admission.Response
+	if err != nil {
+		var apiStatus apierrors.APIStatus
+		if errors.As(err, &apiStatus) {
+			status := apiStatus.Status()
+			resp = admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+		} else {
+			resp = admission.Denied(err.Error())
+		}
+	} else {
+		resp = admission.Allowed("")
+	}
+	resp.Warnings = warnings
+	return resp
+}
@@ -23,6 +23,7 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -85,6 +86,51 @@ func (in *ProjectResourceQuotaList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectResourceQuotaNamespaceStatus) DeepCopyInto(out *ProjectResourceQuotaNamespaceStatus) {
+	*out = *in
+	if in.Used != nil {
+		in, out := &in.Used, &out.Used
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectResourceQuotaNamespaceStatus.
+func (in *ProjectResourceQuotaNamespaceStatus) DeepCopy() *ProjectResourceQuotaNamespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectResourceQuotaNamespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectResourceQuotaReservation) DeepCopyInto(out *ProjectResourceQuotaReservation) {
+	*out = *in
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	in.CreationTimestamp.DeepCopyInto(&out.CreationTimestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectResourceQuotaReservation.
+func (in *ProjectResourceQuotaReservation) DeepCopy() *ProjectResourceQuotaReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectResourceQuotaReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectResourceQuotaSpec) DeepCopyInto(out *ProjectResourceQuotaSpec) {
 	*out = *in
@@ -93,6 +139,11 @@ func (in *ProjectResourceQuotaSpec) DeepCopyInto(out *ProjectResourceQuotaSpec)
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Hard != nil {
 		in, out := &in.Hard, &out.Hard
 		*out = make(corev1.ResourceList, len(*in))
@@ -100,6 +151,23 @@ func (in *ProjectResourceQuotaSpec) DeepCopyInto(out *ProjectResourceQuotaSpec)
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Soft != nil {
+		in, out := &in.Soft, &out.Soft
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]corev1.ResourceQuotaScope, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScopeSelector != nil {
+		in, out := &in.ScopeSelector, &out.ScopeSelector
+		*out = new(corev1.ScopeSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectResourceQuotaSpec.
@@ -122,6 +190,43 @@ func (in *ProjectResourceQuotaStatus) DeepCopyInto(out *ProjectResourceQuotaStat
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]ProjectResourceQuotaReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]ProjectResourceQuotaViolation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MissingNamespaces != nil {
+		in, out := &in.MissingNamespaces, &out.MissingNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]ProjectResourceQuotaNamespaceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectResourceQuotaStatus.
@@ -133,3 +238,19 @@ func (in *ProjectResourceQuotaStatus) DeepCopy() *ProjectResourceQuotaStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectResourceQuotaViolation) DeepCopyInto(out *ProjectResourceQuotaViolation) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectResourceQuotaViolation.
+func (in *ProjectResourceQuotaViolation) DeepCopy() *ProjectResourceQuotaViolation {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectResourceQuotaViolation)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -90,8 +90,9 @@ func main() {
 	}
 
 	if err = (&controller.ProjectResourceQuotaReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("projectresourcequota-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ProjectResourceQuota")
 		os.Exit(1)
@@ -100,32 +101,16 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "ProjectResourceQuota")
 		os.Exit(1)
 	}
-	if err = jentingiov1.SetupPodWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+	if err = jentingiov1.SetupQuotaWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Quota")
 		os.Exit(1)
 	}
-	if err = jentingiov1.SetupServiceWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "Service")
+	if err = jentingiov1.SetupNamespaceWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Namespace")
 		os.Exit(1)
 	}
-	if err = jentingiov1.SetupReplicationControllerWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "ReplicationController")
-		os.Exit(1)
-	}
-	if err = jentingiov1.SetupResourceQuotaWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "ResourceQuota")
-		os.Exit(1)
-	}
-	if err = jentingiov1.SetupSecretWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "Secret")
-		os.Exit(1)
-	}
-	if err = jentingiov1.SetupConfigMapWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "ConfigMap")
-		os.Exit(1)
-	}
-	if err = jentingiov1.SetupPersistentVolumeClaimWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "PersistentVolumeClaim")
+	if err = jentingiov1.SetupObjectCountWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ObjectCount")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
@@ -18,18 +18,30 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -38,15 +50,27 @@ import (
 
 	"github.com/go-logr/logr"
 	jentingiov1 "github.com/jenting/projectresourcequota/api/v1"
+	"github.com/jenting/projectresourcequota/internal/metrics"
+	"github.com/jenting/projectresourcequota/internal/quota"
 )
 
+// FieldManager is the field manager of the patches written by the controller
+const FieldManager = jentingiov1.ControllerFieldManager
+
 // ProjectResourceQuotaReconciler reconciles a ProjectResourceQuota object
 type ProjectResourceQuotaReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// controller watches the resources referenced by the count/<resource>.<group> resources dynamically
+	controller controller.Controller
+	restMapper meta.RESTMapper
+	watchesMu  sync.Mutex
+	watches    map[schema.GroupVersionKind]struct{}
 }
 
-// removeAnnotationFromObjects removes the annotation project-resource-quota from the configmaps/persistentvolumeclaims/pods/replicationcontrollers/resourcequotas/secrets/services.
+// removeAnnotationFromObjects removes the ProjectResourceQuota from the annotation project-resource-quota of the objects tracked by the quota evaluators.
 func (r *ProjectResourceQuotaReconciler) removeAnnotationFromObjects(ctx context.Context, log logr.Logger, prqName string, removedNamespaces sets.String) error {
 	if len(removedNamespaces) == 0 {
 		return nil
@@ -55,173 +79,96 @@ func (r *ProjectResourceQuotaReconciler) removeAnnotationFromObjects(ctx context
 	for _, removedNamespace := range removedNamespaces.List() {
 		log.Info("Reconcile ProjectResourceQuota", "prqName", prqName, "removedNamespace", removedNamespace)
 
-		// remove the annotations from the configmaps.
-		cmList := &corev1.ConfigMapList{}
-		if err := r.Client.List(ctx, cmList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list configmaps")
-			return err
-		}
-		for _, cm := range cmList.Items {
-			if !jentingiov1.IsAnnotationExists(&cm, jentingiov1.ProjectResourceQuotaAnnotation) {
-				continue
-			}
-
-			if err := jentingiov1.RemoveAnnotation(&cm, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from configmap %s/%s", cm.Name, cm.Namespace)
-				return err
-			}
-
-			if err := r.Client.Update(ctx, &cm); err != nil {
-				log.Error(err, "failed to update configmap %s/%s", cm.Name, cm.Namespace)
-				return err
-			}
-		}
-
-		// remove the annotations from the persistentvolumeclaims.
-		pvcList := &corev1.PersistentVolumeClaimList{}
-		if err := r.Client.List(ctx, pvcList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list persistentvolumeclaims")
-			return err
-		}
-		for _, pvc := range pvcList.Items {
-			if !jentingiov1.IsAnnotationExists(&pvc, jentingiov1.ProjectResourceQuotaAnnotation) {
-				continue
-			}
-
-			if err := jentingiov1.RemoveAnnotation(&pvc, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from persistentvolumeclaim %s/%s", pvc.Name, pvc.Namespace)
-				return err
-			}
-
-			if err := r.Client.Update(ctx, &pvc); err != nil {
-				log.Error(err, "failed to update persistentvolumeclaim %s/%s", pvc.Name, pvc.Namespace)
-				return err
-			}
-		}
-
-		// remove the annotations from the pods
-		podList := &corev1.PodList{}
-		if err := r.Client.List(ctx, podList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list pods")
-			return err
-		}
-		for _, pod := range podList.Items {
-			if !jentingiov1.IsAnnotationExists(&pod, jentingiov1.ProjectResourceQuotaAnnotation) {
-				continue
-			}
-
-			if err := jentingiov1.RemoveAnnotation(&pod, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from pod %s/%s", pod.Name, pod.Namespace)
-				return err
-			}
-
-			if err := r.Client.Update(ctx, &pod); err != nil {
-				log.Error(err, "failed to update pod %s/%s", pod.Name, pod.Namespace)
-				return err
-			}
-		}
-
-		// remove the annotations from the replicationcontrollers
-		rcList := &corev1.ReplicationControllerList{}
-		if err := r.Client.List(ctx, rcList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list replicationcontrollers")
-			return err
-		}
-		for _, rc := range rcList.Items {
-			if !jentingiov1.IsAnnotationExists(&rc, jentingiov1.ProjectResourceQuotaAnnotation) {
-				continue
-			}
-
-			if err := jentingiov1.RemoveAnnotation(&rc, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from replicationcontroller %s/%s", rc.Name, rc.Namespace)
-				return err
-			}
-
-			if err := r.Client.Update(ctx, &rc); err != nil {
-				log.Error(err, "failed to update replicationcontroller %s/%s", rc.Name, rc.Namespace)
-				return err
-			}
-		}
-
-		// remove the annotations from the resourcequotas
-		rqList := &corev1.ResourceQuotaList{}
-		if err := r.Client.List(ctx, rqList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list resourcequotas")
-			return err
-		}
-		for _, rq := range rqList.Items {
-			if !jentingiov1.IsAnnotationExists(&rq, jentingiov1.ProjectResourceQuotaAnnotation) {
-				continue
-			}
-
-			if err := jentingiov1.RemoveAnnotation(&rq, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from resourcequota %s/%s", rq.Name, rq.Namespace)
+		for _, evaluator := range quota.Evaluators() {
+			objList := evaluator.NewList()
+			if err := r.Client.List(ctx, objList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
+				log.Error(err, "failed to list objects", "kind", evaluator.Kind())
 				return err
 			}
+			if err := meta.EachListItem(objList, func(o runtime.Object) error {
+				obj := o.(client.Object)
+				if !jentingiov1.IsAttributedTo(obj, prqName) {
+					return nil
+				}
 
-			if err := r.Client.Update(ctx, &rq); err != nil {
-				log.Error(err, "failed to update resourcequota %s/%s", rq.Name, rq.Namespace)
+				if err := r.patchObject(ctx, obj, func() error { return jentingiov1.UnattributeFrom(obj, prqName) }); err != nil {
+					log.Error(err, "failed to remove annotation from object", "kind", evaluator.Kind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+					return err
+				}
+				return nil
+			}); err != nil {
 				return err
 			}
 		}
+	}
+	return nil
+}
 
-		// remove the annotations from the secrets
-		secretList := &corev1.SecretList{}
-		if err := r.Client.List(ctx, secretList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list secrets")
-			return err
-		}
-		for _, secret := range secretList.Items {
-			if !jentingiov1.IsAnnotationExists(&secret, jentingiov1.ProjectResourceQuotaAnnotation) {
-				continue
-			}
-
-			if err := jentingiov1.RemoveAnnotation(&secret, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from secret %s/%s", secret.Name, secret.Namespace)
-				return err
-			}
-
-			if err := r.Client.Update(ctx, &secret); err != nil {
-				log.Error(err, "failed to update secret %s/%s", secret.Name, secret.Namespace)
-				return err
-			}
+// attributeObjects adds the ProjectResourceQuota to the annotation project-resource-quota of the existing objects tracked by the quota evaluators
+// which are created before the projectresourcequota is created or before the namespace joins the project,
+// and removes the ProjectResourceQuotas the namespace no longer belongs to, e.g. the namespace moved from another project.
+// The annotation is set to the ProjectResourceQuotas derived from the namespace as the admission webhooks validate it.
+// The object failed to be attributed does not stop attributing the others, the errors are aggregated.
+func (r *ProjectResourceQuotaReconciler) attributeObjects(ctx context.Context, log logr.Logger, prq *jentingiov1.ProjectResourceQuota, namespaces sets.String) error {
+	var errs []error
+	for _, namespace := range namespaces.List() {
+		// the projectresourcequotas.jenting.io CRs the namespace belongs to currently
+		prqs, err := jentingiov1.GetProjectResourceQuotas(ctx, r.Client, namespace)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
 
-		// remove the annotations from the services
-		svcList := &corev1.ServiceList{}
-		if err := r.Client.List(ctx, svcList, &client.ListOptions{Namespace: removedNamespace}); err != nil {
-			log.Error(err, "failed to list services")
-			return err
-		}
-		for _, svc := range svcList.Items {
-			if !jentingiov1.IsAnnotationExists(&svc, jentingiov1.ProjectResourceQuotaAnnotation) {
+		for _, evaluator := range quota.Evaluators() {
+			objList := evaluator.NewList()
+			if err := r.Client.List(ctx, objList, &client.ListOptions{Namespace: namespace}); err != nil {
+				log.Error(err, "failed to list objects", "kind", evaluator.Kind())
+				errs = append(errs, err)
 				continue
 			}
+			if err := meta.EachListItem(objList, func(o runtime.Object) error {
+				obj := o.(client.Object)
+				// the projects tracking the resources of the kind whose spec.scopes and spec.scopeSelector the object matches
+				prqNames, err := jentingiov1.AttributableProjectResourceQuotas(evaluator, obj, prqs)
+				if err != nil {
+					errs = append(errs, err)
+					return nil
+				}
+				attributed := sets.NewString(jentingiov1.ProjectResourceQuotaNames(obj)...)
+				if attributed.Equal(prqNames) {
+					return nil
+				}
 
-			if err := jentingiov1.RemoveAnnotation(&svc, jentingiov1.ProjectResourceQuotaAnnotation); err != nil {
-				log.Error(err, "failed to remove annotation from service %s/%s", svc.Name, svc.Namespace)
-				return err
-			}
-
-			if err := r.Client.Update(ctx, &svc); err != nil {
-				log.Error(err, "failed to update service %s/%s", svc.Name, svc.Namespace)
-				return err
+				if err := r.patchObject(ctx, obj, func() error { return jentingiov1.SetAttribution(obj, prqNames.List()) }); err != nil {
+					log.Error(err, "failed to update annotation of object", "kind", evaluator.Kind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+					errs = append(errs, err)
+					return nil
+				}
+				if stale := attributed.Difference(prqNames); stale.Len() > 0 {
+					log.Info("Stale attribution removed", "kind", evaluator.Kind(), "name", obj.GetName(), "namespace", obj.GetNamespace(), "prqNames", stale.List())
+				}
+				return nil
+			}); err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
 //+kubebuilder:rbac:groups=jenting.io,resources=projectresourcequotas,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=jenting.io,resources=projectresourcequotas/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=jenting.io,resources=projectresourcequotas/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update
-//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;update
-//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update
-//+kubebuilder:rbac:groups="",resources=replicationcontrollers,verbs=get;list;watch;update
-//+kubebuilder:rbac:groups="",resources=resourcequotas,verbs=get;list;watch;update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update
-//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=replicationcontrollers,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=resourcequotas,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -236,6 +183,7 @@ func (r *ProjectResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl
 	prq := &jentingiov1.ProjectResourceQuota{}
 	if err := r.Get(ctx, req.NamespacedName, prq); err != nil {
 		if errors.IsNotFound(err) {
+			metrics.Delete(req.Name)
 			return ctrl.Result{}, nil
 		}
 
@@ -243,346 +191,656 @@ func (r *ProjectResourceQuotaReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, err
 	}
 
+	// resolve the namespaces within the project
+	namespaces, err := jentingiov1.GetProjectNamespaces(ctx, r.Client, prq)
+	if err != nil {
+		log.Error(err, "unable to resolve project namespaces")
+		r.updateDegradedStatus(ctx, log, prq, "ResolveNamespacesFailed", err)
+		return ctrl.Result{}, err
+	}
+
+	// the namespaces reconciled last time, the annotation is removed from the objects of the namespaces that left the project
+	// regardless of how the projectresourcequota is modified, e.g. kubectl edit, server-side apply or the API clients
+	reconciledNamespaces := sets.NewString()
+	for _, namespaceStatus := range prq.Status.Namespaces {
+		reconciledNamespaces.Insert(namespaceStatus.Namespace)
+	}
+
 	// the projectresourcequota is under deletion
 	if prq.DeletionTimestamp != nil {
-		removedNamespaces := sets.NewString(prq.Spec.Namespaces...)
+		removedNamespaces := namespaces.Union(reconciledNamespaces)
 		log.Info("Delete ProjectResourceQuota", "removedNamespace", removedNamespaces)
 
 		if err := r.removeAnnotationFromObjects(ctx, log, prq.Name, removedNamespaces); err != nil {
 			log.Error(err, "failed to remove annotation from objects")
+			r.Recorder.Eventf(prq, corev1.EventTypeWarning, "AnnotationCleanupFailed", "Failed to remove the annotation from the objects: %v", err)
 			return ctrl.Result{}, err
 		}
 
+		// the finalizers list is replaced by the merge patch, guard it by the resource version
 		log.Info("Delete ProjectResourceQuota", "remove finalizer", jentingiov1.ProjectResourceQuotaFinalizer)
+		base := prq.DeepCopy()
 		if err := jentingiov1.RemoveFinalizer(jentingiov1.ProjectResourceQuotaFinalizer, prq); err != nil {
 			log.Error(err, "failed to remove finalizer")
 			return ctrl.Result{}, err
 		}
 
-		log.Info("Delete ProjectResourceQuota", "update projectresourcequota resource", prq.Name)
-		if err := r.Update(ctx, prq); err != nil {
+		log.Info("Delete ProjectResourceQuota", "patch projectresourcequota resource", prq.Name)
+		if err := r.Patch(ctx, prq, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}), client.FieldOwner(FieldManager)); err != nil {
 			return ctrl.Result{}, err
 		}
+		metrics.Delete(prq.Name)
 		return ctrl.Result{}, nil
 	}
 
-	// handle the projectresourcequota spec.namespaces removal
-	anno, ok := prq.Annotations[corev1.LastAppliedConfigAnnotation]
-	if ok {
-		oldPrq := &jentingiov1.ProjectResourceQuota{}
-		if err := json.Unmarshal([]byte(anno), oldPrq); err != nil {
-			log.Error(err, "failed to unmarshal last applied config annotation")
-			return ctrl.Result{}, nil
-		}
-
-		// handle the namespapce removal from the spec.namespaces
-		oldNamespaces := sets.NewString(oldPrq.Spec.Namespaces...)
-		newNamespaces := sets.NewString(prq.Spec.Namespaces...)
-		removedNamespaces := oldNamespaces.Difference(newNamespaces)
-
-		log.Info("Reconcile ProjectResourceQuota", "oldNamespaces", oldNamespaces, "newNamespaces", newNamespaces, "removedNamespace", removedNamespaces)
+	// handle the namespaces removal from the project since the last reconciliation,
+	// the status.namespaces is kept until the removal succeeds so the failed removal is retried
+	removedNamespaces := reconciledNamespaces.Difference(namespaces)
+	if removedNamespaces.Len() > 0 {
+		log.Info("Reconcile ProjectResourceQuota", "oldNamespaces", reconciledNamespaces, "newNamespaces", namespaces, "removedNamespace", removedNamespaces)
 		if err := r.removeAnnotationFromObjects(ctx, log, prq.Name, removedNamespaces); err != nil {
+			r.updateDegradedStatus(ctx, log, prq, "AnnotationCleanupFailed", err)
 			return ctrl.Result{}, err
 		}
+		for _, namespace := range removedNamespaces.List() {
+			r.Recorder.Eventf(prq, corev1.EventTypeNormal, "NamespaceRemoved", "Namespace %s is removed from the project", namespace)
+		}
 	}
 
-	if prq.Status.Used == nil {
-		prq.Status.Used = make(corev1.ResourceList)
-	} else {
-		// clean up the status.used before calculation
-		for k := range prq.Status.Used {
-			delete(prq.Status.Used, k)
+	// record the namespaces listed in the project which do not exist, e.g. deleted or not created yet
+	missingNamespaces, err := r.missingNamespaces(ctx, namespaces)
+	if err != nil {
+		log.Error(err, "failed to get namespaces")
+		r.updateDegradedStatus(ctx, log, prq, "ResolveNamespacesFailed", err)
+		return ctrl.Result{}, err
+	}
+	for _, namespace := range missingNamespaces {
+		if !sets.NewString(prq.Status.MissingNamespaces...).Has(namespace) {
+			r.Recorder.Eventf(prq, corev1.EventTypeWarning, "NamespaceMissing", "Namespace %s within the project does not exist", namespace)
 		}
 	}
 
+	// attribute the objects existing before the projectresourcequota is created or the namespace joins the project,
+	// and re-attribute the objects still attributed to the projects the namespace moved from
+	// the failed attribution does not block calculating the used resources, it is reported and retried afterwards
+	attributeErr := r.attributeObjects(ctx, log, prq, namespaces)
+	if attributeErr != nil {
+		log.Error(attributeErr, "failed to add annotation to objects")
+	}
+
 	// calculate the current used resources within the project (across multiple namespaces)
-	for _, namespace := range prq.Spec.Namespaces {
-		// ConfigMap
-		if _, found := prq.Spec.Hard[corev1.ResourceConfigMaps]; found {
-			cmList := &corev1.ConfigMapList{}
-			if err := r.Client.List(ctx, cmList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
+	now := time.Now()
+	namespaceStatuses, observed, requeueAfter, err := r.calculateUsed(ctx, prq, namespaces, now)
+	if err != nil {
+		log.Error(err, "failed to calculate used resources")
+		r.updateDegradedStatus(ctx, log, prq, "CalculateUsedFailed", err)
+		return ctrl.Result{}, err
+	}
+	var settleRequeueAfter time.Duration
+	if err := r.patchStatus(ctx, prq, func(prq *jentingiov1.ProjectResourceQuota) {
+		prq.Status.MissingNamespaces = missingNamespaces
+		prq.Status.Namespaces = nil
+		prq.Status.Used = corev1.ResourceList{}
+		for _, namespaceStatus := range namespaceStatuses {
+			prq.Status.Namespaces = append(prq.Status.Namespaces, *namespaceStatus.DeepCopy())
+			for resourceName, quantity := range namespaceStatus.Used {
+				used := prq.Status.Used[resourceName]
+				used.Add(quantity)
+				prq.Status.Used[resourceName] = used
 			}
+		}
 
-			var count int
-			for _, cm := range cmList.Items {
-				if jentingiov1.IsAnnotationExists(&cm, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
-				}
-			}
+		// charge the reservations of the admitted objects that are not observed yet,
+		// the reservations are the latest ones since the admission webhooks add them concurrently
+		settleRequeueAfter = r.settleReservations(prq, observed, now)
 
-			used := prq.Status.Used[corev1.ResourceConfigMaps]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourceConfigMaps] = used
-		}
+		setReadyStatus(prq)
+		setNamespacesStatus(prq)
+		prq.Status.ObservedGeneration = prq.Generation
+		prq.Status.LastReconcileTime = &metav1.Time{Time: now}
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	requeueAfter = minRequeueAfter(requeueAfter, settleRequeueAfter)
 
-		// PersistentVolumeClaim
-		if _, found := prq.Spec.Hard[corev1.ResourcePersistentVolumeClaims]; found {
-			pvcList := &corev1.PersistentVolumeClaimList{}
-			if err := r.Client.List(ctx, pvcList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
-			}
+	// publish the hard and the used resources per namespace
+	metrics.SetHard(prq.Name, prq.Spec.Hard)
+	namespaceUsed := map[string]corev1.ResourceList{}
+	for _, namespaceStatus := range prq.Status.Namespaces {
+		namespaceUsed[namespaceStatus.Namespace] = namespaceStatus.Used
+	}
+	metrics.SetUsed(prq.Name, namespaceUsed)
 
-			var count int
-			for _, pvc := range pvcList.Items {
-				if jentingiov1.IsAnnotationExists(&pvc, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
-				}
+	if attributeErr != nil {
+		r.updateDegradedStatus(ctx, log, prq, "AddAnnotationFailed", attributeErr)
+		return ctrl.Result{}, attributeErr
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// missingNamespaces returns the sorted namespaces which do not exist among the given namespaces
+func (r *ProjectResourceQuotaReconciler) missingNamespaces(ctx context.Context, namespaces sets.String) ([]string, error) {
+	var missing []string
+	for _, namespace := range namespaces.List() {
+		if err := r.Get(ctx, types.NamespacedName{Name: namespace}, &corev1.Namespace{}); err != nil {
+			if errors.IsNotFound(err) {
+				missing = append(missing, namespace)
+				continue
 			}
+			return nil, err
+		}
+	}
+	return missing, nil
+}
 
-			used := prq.Status.Used[corev1.ResourcePersistentVolumeClaims]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourcePersistentVolumeClaims] = used
+// calculateUsed calculates the current used resources per namespace within the project,
+// and records the objects observed to settle the reservations.
+// It returns the duration after which the used resources need to be recalculated, e.g. a pod deletion grace period passes.
+func (r *ProjectResourceQuotaReconciler) calculateUsed(ctx context.Context, prq *jentingiov1.ProjectResourceQuota, namespaces sets.String, now time.Time) ([]jentingiov1.ProjectResourceQuotaNamespaceStatus, map[types.UID]string, time.Duration, error) {
+	var namespaceStatuses []jentingiov1.ProjectResourceQuotaNamespaceStatus
+	observed := map[types.UID]string{}
+	var requeueAfter time.Duration
+	for _, namespace := range namespaces.List() {
+		used, namespaceRequeueAfter, err := r.calculateNamespaceUsed(ctx, prq, namespace, now, observed)
+		if err != nil {
+			return nil, nil, 0, err
 		}
+		namespaceStatuses = append(namespaceStatuses, jentingiov1.ProjectResourceQuotaNamespaceStatus{
+			Namespace: namespace,
+			Used:      used,
+		})
+		requeueAfter = minRequeueAfter(requeueAfter, namespaceRequeueAfter)
+	}
+	return namespaceStatuses, observed, requeueAfter, nil
+}
+
+// calculateNamespaceUsed calculates the current used resources within the namespace,
+// and records the objects observed to settle the reservations.
+func (r *ProjectResourceQuotaReconciler) calculateNamespaceUsed(ctx context.Context, prq *jentingiov1.ProjectResourceQuota, namespace string, now time.Time, observed map[types.UID]string) (corev1.ResourceList, time.Duration, error) {
+	used := corev1.ResourceList{}
+	var requeueAfter time.Duration
 
-		// Pod
-		if _, found := prq.Spec.Hard[corev1.ResourcePods]; found {
-			podList := &corev1.PodList{}
-			if err := r.Client.List(ctx, podList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
+	for _, evaluator := range quota.Evaluators() {
+		// the resources set and tracked by the kind, the unused resources are reported as zero
+		usage := corev1.ResourceList{}
+		for resourceName := range prq.Spec.Hard {
+			if evaluator.Handles(resourceName) {
+				usage[resourceName] = resource.Quantity{}
 			}
+		}
+		if len(usage) == 0 {
+			continue
+		}
 
-			var count int
-			for _, pvc := range podList.Items {
-				if jentingiov1.IsAnnotationExists(&pvc, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
+		objList := evaluator.NewList()
+		if err := r.Client.List(ctx, objList, &client.ListOptions{Namespace: namespace}); err != nil {
+			return nil, 0, err
+		}
+		if err := meta.EachListItem(objList, func(o runtime.Object) error {
+			obj := o.(client.Object)
+			// count the objects within the project only
+			if !jentingiov1.IsAttributedTo(obj, prq.Name) {
+				return nil
+			}
+			observed[obj.GetUID()] = obj.GetResourceVersion()
+
+			if pod, ok := obj.(*corev1.Pod); ok {
+				// skip the terminal pods and the pods whose deletion grace period has passed
+				if !quota.QuotaV1Pod(pod, now) {
+					return nil
 				}
-			}
-			used := prq.Status.Used[corev1.ResourcePods]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourcePods] = used
-
-			var requestCPU, requestMemory, requestStorage, requestEphemeralStorage resource.Quantity
-			var limitCPU, limitMemory, limitEphemeralStorage resource.Quantity
-			for _, pod := range podList.Items {
-				for _, container := range pod.Spec.Containers {
-					// requests
-					quality := container.Resources.Requests.Cpu()
-					if quality != nil {
-						requestCPU.Add(*quality)
-					}
-					quality = container.Resources.Requests.Memory()
-					if quality != nil {
-						requestMemory.Add(*quality)
-					}
-					quality = container.Resources.Requests.Storage()
-					if quality != nil {
-						requestStorage.Add(*quality)
-					}
-					quality = container.Resources.Requests.StorageEphemeral()
-					if quality != nil {
-						requestEphemeralStorage.Add(*quality)
-					}
-
-					// limits
-					quality = container.Resources.Limits.Cpu()
-					if quality != nil {
-						limitCPU.Add(*quality)
-					}
-					quality = container.Resources.Limits.Memory()
-					if quality != nil {
-						limitMemory.Add(*quality)
-					}
-					quality = container.Resources.Limits.StorageEphemeral()
-					if quality != nil {
-						limitEphemeralStorage.Add(*quality)
-					}
+				// recalculate once the deletion grace period passes
+				if expiration, ok := quota.DeletionGracePeriodExpiration(pod); ok {
+					requeueAfter = minRequeueAfter(requeueAfter, expiration.Sub(now))
 				}
 			}
-
-			if _, found := prq.Spec.Hard[corev1.ResourceCPU]; found {
-				used := prq.Status.Used[corev1.ResourceCPU]
-				used.Add(requestCPU)
-				prq.Status.Used[corev1.ResourceCPU] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceRequestsCPU]; found {
-				used := prq.Status.Used[corev1.ResourceRequestsCPU]
-				used.Add(requestCPU)
-				prq.Status.Used[corev1.ResourceRequestsCPU] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceMemory]; found {
-				used := prq.Status.Used[corev1.ResourceMemory]
-				used.Add(requestMemory)
-				prq.Status.Used[corev1.ResourceMemory] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceRequestsMemory]; found {
-				used := prq.Status.Used[corev1.ResourceRequestsMemory]
-				used.Add(requestMemory)
-				prq.Status.Used[corev1.ResourceRequestsMemory] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceStorage]; found {
-				used := prq.Status.Used[corev1.ResourceStorage]
-				used.Add(requestStorage)
-				prq.Status.Used[corev1.ResourceStorage] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceRequestsStorage]; found {
-				used := prq.Status.Used[corev1.ResourceRequestsStorage]
-				used.Add(requestStorage)
-				prq.Status.Used[corev1.ResourceRequestsStorage] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceEphemeralStorage]; found {
-				used := prq.Status.Used[corev1.ResourceEphemeralStorage]
-				used.Add(requestEphemeralStorage)
-				prq.Status.Used[corev1.ResourceEphemeralStorage] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceRequestsEphemeralStorage]; found {
-				used := prq.Status.Used[corev1.ResourceRequestsEphemeralStorage]
-				used.Add(requestEphemeralStorage)
-				prq.Status.Used[corev1.ResourceRequestsEphemeralStorage] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceLimitsCPU]; found {
-				used := prq.Status.Used[corev1.ResourceLimitsCPU]
-				used.Add(limitCPU)
-				prq.Status.Used[corev1.ResourceLimitsCPU] = used
+			// skip the objects not matching the spec.scopes and spec.scopeSelector, e.g. spec.activeDeadlineSeconds is updated
+			matched, err := evaluator.Matches(obj, prq.Spec.Scopes, prq.Spec.ScopeSelector)
+			if err != nil {
+				return err
 			}
-			if _, found := prq.Spec.Hard[corev1.ResourceLimitsMemory]; found {
-				used := prq.Status.Used[corev1.ResourceLimitsMemory]
-				used.Add(limitMemory)
-				prq.Status.Used[corev1.ResourceLimitsMemory] = used
+			if !matched {
+				return nil
 			}
-			if _, found := prq.Spec.Hard[corev1.ResourceLimitsEphemeralStorage]; found {
-				used := prq.Status.Used[corev1.ResourceLimitsEphemeralStorage]
-				used.Add(limitEphemeralStorage)
-				prq.Status.Used[corev1.ResourceLimitsEphemeralStorage] = used
+
+			for resourceName, quantity := range evaluator.Usage(obj) {
+				if total, found := usage[resourceName]; found {
+					total.Add(quantity)
+					usage[resourceName] = total
+				}
 			}
+			return nil
+		}); err != nil {
+			return nil, 0, err
 		}
 
-		// ReplicationController
-		if _, found := prq.Spec.Hard[corev1.ResourceReplicationControllers]; found {
-			rcList := &corev1.ReplicationControllerList{}
-			if err := r.Client.List(ctx, rcList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
-			}
+		for resourceName, quantity := range usage {
+			total := used[resourceName]
+			total.Add(quantity)
+			used[resourceName] = total
+		}
+	}
 
-			var count int
-			for _, pvc := range rcList.Items {
-				if jentingiov1.IsAnnotationExists(&pvc, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
-				}
-			}
-			used := prq.Status.Used[corev1.ResourceReplicationControllers]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourceReplicationControllers] = used
+	// count/<resource>.<group>
+	for resourceName := range prq.Spec.Hard {
+		if !quota.IsObjectCountResource(resourceName) {
+			continue
 		}
 
-		// ResourceQuota
-		if _, found := prq.Spec.Hard[corev1.ResourceQuotas]; found {
-			rqList := &corev1.ResourceQuotaList{}
-			if err := r.Client.List(ctx, rqList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
-			}
+		count, err := r.countObjects(ctx, resourceName, namespace, observed)
+		if err != nil {
+			return nil, 0, err
+		}
+		quantity := used[resourceName]
+		quantity.Add(resource.MustParse(fmt.Sprintf("%d", count)))
+		used[resourceName] = quantity
+	}
 
-			var count int
-			for _, rq := range rqList.Items {
-				if jentingiov1.IsAnnotationExists(&rq, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
-				}
-			}
-			used := prq.Status.Used[corev1.ResourceQuotas]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourceQuotas] = used
+	return used, requeueAfter, nil
+}
+
+// countObjects counts the objects of the count/<resource>.<group> resource within the namespace,
+// and records the objects observed to settle the reservations.
+// The resource not served by the API server, e.g. the CRD is not installed yet, has no objects.
+func (r *ProjectResourceQuotaReconciler) countObjects(ctx context.Context, resourceName corev1.ResourceName, namespace string, observed map[types.UID]string) (int, error) {
+	groupResource, ok := quota.ObjectCountGroupResource(resourceName)
+	if !ok {
+		return 0, nil
+	}
+
+	gvk, err := r.restMapper.KindFor(groupResource.WithVersion(""))
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return 0, nil
 		}
+		return 0, err
+	}
 
-		// Secret
-		if _, found := prq.Spec.Hard[corev1.ResourceSecrets]; found {
-			secretList := &corev1.SecretList{}
-			if err := r.Client.List(ctx, secretList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
-			}
+	// watch the resource to recalculate the used resources once the objects are created or deleted
+	if err := r.watchObjects(gvk); err != nil {
+		return 0, err
+	}
 
-			var count int
-			for _, secret := range secretList.Items {
-				if jentingiov1.IsAnnotationExists(&secret, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
-				}
-			}
-			used := prq.Status.Used[corev1.ResourceSecrets]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourceSecrets] = used
+	objList := &metav1.PartialObjectMetadataList{}
+	objList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.Client.List(ctx, objList, &client.ListOptions{Namespace: namespace}); err != nil {
+		return 0, err
+	}
+	for _, obj := range objList.Items {
+		observed[obj.UID] = obj.ResourceVersion
+	}
+	return len(objList.Items), nil
+}
+
+// watchObjects watches the metadata of the objects of the kind, if it is not watched yet
+func (r *ProjectResourceQuotaReconciler) watchObjects(gvk schema.GroupVersionKind) error {
+	r.watchesMu.Lock()
+	defer r.watchesMu.Unlock()
+
+	if _, found := r.watches[gvk]; found {
+		return nil
+	}
+
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	if err := r.controller.Watch(&source.Kind{Type: obj},
+		handler.EnqueueRequestsFromMapFunc(r.findNamespaceProjectResourceQuotas),
+		// the object count changes on creation and deletion only
+		predicate.Funcs{UpdateFunc: func(event.UpdateEvent) bool { return false }},
+	); err != nil {
+		return err
+	}
+	r.watches[gvk] = struct{}{}
+	return nil
+}
+
+// settleReservations drops the reservations of the objects counted already or the reservations expired,
+// and charges the remaining reservations to status.used.
+// It returns the duration after which the next reservation expires.
+func (r *ProjectResourceQuotaReconciler) settleReservations(prq *jentingiov1.ProjectResourceQuota, observed map[types.UID]string, now time.Time) time.Duration {
+	var requeueAfter time.Duration
+	var reservations []jentingiov1.ProjectResourceQuotaReservation
+	for _, reservation := range prq.Status.Reservations {
+		// the controller observes the object after the admission
+		if resourceVersion, found := observed[reservation.UID]; found && resourceVersion != reservation.ResourceVersion {
+			continue
+		}
+
+		// the object is never observed, e.g. the object creation failed after the admission
+		expiration := reservation.CreationTimestamp.Add(jentingiov1.ReservationTimeout)
+		if !now.Before(expiration) {
+			continue
 		}
 
-		// Service
-		if _, found := prq.Spec.Hard[corev1.ResourceServices]; found {
-			svcList := &corev1.ServiceList{}
-			if err := r.Client.List(ctx, svcList, &client.ListOptions{Namespace: namespace}); err != nil {
-				return ctrl.Result{}, err
+		for resourceName, quantity := range reservation.Usage {
+			if _, found := prq.Spec.Hard[resourceName]; !found {
+				continue
 			}
+			used := prq.Status.Used[resourceName]
+			used.Add(quantity)
+			prq.Status.Used[resourceName] = used
 
-			var count, npCount, lbCount int
-			for _, svc := range svcList.Items {
-				if jentingiov1.IsAnnotationExists(&svc, jentingiov1.ProjectResourceQuotaAnnotation) {
-					count++
-
-					switch svc.Spec.Type {
-					case corev1.ServiceTypeNodePort:
-						npCount++
-					case corev1.ServiceTypeLoadBalancer:
-						lbCount++
-					}
+			// charge the namespace the object is admitted to as well
+			for i := range prq.Status.Namespaces {
+				if prq.Status.Namespaces[i].Namespace != reservation.Namespace {
+					continue
 				}
+				used := prq.Status.Namespaces[i].Used[resourceName]
+				used.Add(quantity)
+				prq.Status.Namespaces[i].Used[resourceName] = used
 			}
-			used := prq.Status.Used[corev1.ResourceServices]
-			used.Add(resource.MustParse(fmt.Sprintf("%d", count)))
-			prq.Status.Used[corev1.ResourceServices] = used
-
-			if _, found := prq.Spec.Hard[corev1.ResourceServicesNodePorts]; found {
-				used := prq.Status.Used[corev1.ResourceServicesNodePorts]
-				used.Add(resource.MustParse(fmt.Sprintf("%d", npCount)))
-				prq.Status.Used[corev1.ResourceServicesNodePorts] = used
-			}
-			if _, found := prq.Spec.Hard[corev1.ResourceServicesLoadBalancers]; found {
-				used := prq.Status.Used[corev1.ResourceServicesLoadBalancers]
-				used.Add(resource.MustParse(fmt.Sprintf("%d", lbCount)))
-				prq.Status.Used[corev1.ResourceServicesLoadBalancers] = used
+		}
+		reservations = append(reservations, reservation)
+
+		requeueAfter = minRequeueAfter(requeueAfter, expiration.Sub(now))
+	}
+	prq.Status.Reservations = reservations
+	return requeueAfter
+}
+
+// setReadyStatus sets the status conditions after the used resources are calculated
+func setReadyStatus(prq *jentingiov1.ProjectResourceQuota) {
+	meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+		Type:               jentingiov1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Reconciled",
+		Message:            "The used resources are calculated",
+		ObservedGeneration: prq.Generation,
+	})
+	meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+		Type:               jentingiov1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Reconciled",
+		Message:            "The used resources are calculated",
+		ObservedGeneration: prq.Generation,
+	})
+
+	// the used resources exceed the hard limits, e.g. the hard limits are lowered or the objects existed before
+	var exceeded []string
+	for _, resourceName := range sortedResourceNames(prq.Spec.Hard) {
+		hard := prq.Spec.Hard[resourceName]
+		used := prq.Status.Used[resourceName]
+		if used.Cmp(hard) == 1 {
+			exceeded = append(exceeded, fmt.Sprintf("%s: used %s > hard limit %s", resourceName, used.String(), hard.String()))
+		}
+	}
+	if len(exceeded) > 0 {
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionOverQuota,
+			Status:             metav1.ConditionTrue,
+			Reason:             "UsedExceedsHard",
+			Message:            strings.Join(exceeded, ", "),
+			ObservedGeneration: prq.Generation,
+		})
+	} else {
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionOverQuota,
+			Status:             metav1.ConditionFalse,
+			Reason:             "UsedWithinHard",
+			Message:            "The used resources are within the hard limits",
+			ObservedGeneration: prq.Generation,
+		})
+	}
+
+	// the used resources exceed the soft limits, the objects are still admitted
+	var softExceeded []string
+	for _, resourceName := range sortedResourceNames(prq.Spec.Soft) {
+		soft := prq.Spec.Soft[resourceName]
+		used := prq.Status.Used[resourceName]
+		if used.Cmp(soft) == 1 {
+			softExceeded = append(softExceeded, fmt.Sprintf("%s: used %s > soft limit %s", resourceName, used.String(), soft.String()))
+		}
+	}
+	if len(softExceeded) > 0 {
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionSoftLimitExceeded,
+			Status:             metav1.ConditionTrue,
+			Reason:             "UsedExceedsSoft",
+			Message:            strings.Join(softExceeded, ", "),
+			ObservedGeneration: prq.Generation,
+		})
+	} else {
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionSoftLimitExceeded,
+			Status:             metav1.ConditionFalse,
+			Reason:             "UsedWithinSoft",
+			Message:            "The used resources are within the soft limits",
+			ObservedGeneration: prq.Generation,
+		})
+	}
+}
+
+// setNamespacesStatus sets the status condition of the namespaces listed in the project which do not exist
+func setNamespacesStatus(prq *jentingiov1.ProjectResourceQuota) {
+	if len(prq.Status.MissingNamespaces) > 0 {
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionNamespacesMissing,
+			Status:             metav1.ConditionTrue,
+			Reason:             "NamespacesNotFound",
+			Message:            fmt.Sprintf("The namespaces %s do not exist", strings.Join(prq.Status.MissingNamespaces, ", ")),
+			ObservedGeneration: prq.Generation,
+		})
+		return
+	}
+	meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+		Type:               jentingiov1.ConditionNamespacesMissing,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NamespacesFound",
+		Message:            "The namespaces within the project exist",
+		ObservedGeneration: prq.Generation,
+	})
+}
+
+// patchObject patches the object mutated by the mutate function with a merge patch of the changed fields only,
+// so the concurrent writers of the other fields, e.g. the GitOps tools or the HPA, do not conflict with the controller.
+// The object deleted meanwhile is ignored.
+func (r *ProjectResourceQuotaReconciler) patchObject(ctx context.Context, obj client.Object, mutate func() error) error {
+	base := obj.DeepCopyObject().(client.Object)
+	if err := mutate(); err != nil {
+		return err
+	}
+	return client.IgnoreNotFound(r.Patch(ctx, obj, client.MergeFrom(base), client.FieldOwner(FieldManager)))
+}
+
+// patchStatus patches the status mutated by the mutate function with a merge patch guarded by the resource version,
+// otherwise the status.reservations added by the admission webhooks concurrently might be lost.
+// On conflict, the mutation is applied again to the latest ProjectResourceQuota.
+func (r *ProjectResourceQuotaReconciler) patchStatus(ctx context.Context, prq *jentingiov1.ProjectResourceQuota, mutate func(prq *jentingiov1.ProjectResourceQuota)) error {
+	attempted := false
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if attempted {
+			latest := &jentingiov1.ProjectResourceQuota{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(prq), latest); err != nil {
+				return err
 			}
+			*prq = *latest
 		}
+		attempted = true
+
+		base := prq.DeepCopy()
+		mutate(prq)
+		return r.Status().Patch(ctx, prq, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}),
+			&client.SubResourcePatchOptions{PatchOptions: client.PatchOptions{FieldManager: FieldManager}})
+	})
+}
+
+// updateDegradedStatus records the reconcile failure in the status conditions
+func (r *ProjectResourceQuotaReconciler) updateDegradedStatus(ctx context.Context, log logr.Logger, prq *jentingiov1.ProjectResourceQuota, reason string, reconcileErr error) {
+	r.Recorder.Event(prq, corev1.EventTypeWarning, reason, reconcileErr.Error())
+
+	if err := r.patchStatus(ctx, prq, func(prq *jentingiov1.ProjectResourceQuota) {
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			Message:            reconcileErr.Error(),
+			ObservedGeneration: prq.Generation,
+		})
+		meta.SetStatusCondition(&prq.Status.Conditions, metav1.Condition{
+			Type:               jentingiov1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            reconcileErr.Error(),
+			ObservedGeneration: prq.Generation,
+		})
+		prq.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+	}); err != nil {
+		log.Error(err, "failed to update degraded status")
 	}
+}
 
-	if err := r.Status().Update(ctx, prq); err != nil {
-		return ctrl.Result{}, err
+// sortedResourceNames returns the resource names in order
+func sortedResourceNames(rl corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(rl))
+	for name := range rl {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// minRequeueAfter returns the shorter requeue duration, zero means no requeue
+func minRequeueAfter(a, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
 	}
-	return ctrl.Result{}, nil
+	return a
+}
+
+// reservationsChangedPredicate passes the updates changing status.reservations,
+// otherwise the reservation of the object never created is not expired in the project without other events.
+var reservationsChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPRQ, ok := e.ObjectOld.(*jentingiov1.ProjectResourceQuota)
+		if !ok {
+			return false
+		}
+		newPRQ, ok := e.ObjectNew.(*jentingiov1.ProjectResourceQuota)
+		if !ok {
+			return false
+		}
+		return !equality.Semantic.DeepEqual(oldPRQ.Status.Reservations, newPRQ.Status.Reservations)
+	},
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ProjectResourceQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&jentingiov1.ProjectResourceQuota{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
-		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, // ConfigMap
-			handler.EnqueueRequestsFromMapFunc(r.findObjects),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
-		).
-		Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, // PersistentVolumeClaim
-			handler.EnqueueRequestsFromMapFunc(r.findObjects),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+	b := ctrl.NewControllerManagedBy(mgr).
+		// the status updates are not reconciled, otherwise status.lastReconcileTime triggers the reconciliation endlessly,
+		// except the status.reservations added by the admission webhooks which are settled or expired by the reconciliation
+		For(&jentingiov1.ProjectResourceQuota{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, reservationsChangedPredicate))).
+		// the children namespaces change the namespaces within the parent project
+		Watches(&source.Kind{Type: &jentingiov1.ProjectResourceQuota{}},
+			handler.EnqueueRequestsFromMapFunc(r.findParentProjectResourceQuotas),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
 		).
-		Watches(&source.Kind{Type: &corev1.Pod{}}, // Pod
+		// the namespace creation and deletion change the missing namespaces, the labels change the selected namespaces
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, // Namespace
+			handler.EnqueueRequestsFromMapFunc(r.findProjectResourceQuotas),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		)
+	// the objects tracked by the quota evaluators change the used resources
+	for _, evaluator := range quota.Evaluators() {
+		b = b.Watches(&source.Kind{Type: evaluator.NewObject()},
 			handler.EnqueueRequestsFromMapFunc(r.findObjects),
 			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
-		).
-		Watches(&source.Kind{Type: &corev1.ReplicationController{}}, // ReplicationController
-			handler.EnqueueRequestsFromMapFunc(r.findObjects),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
-		).
-		Watches(&source.Kind{Type: &corev1.ResourceQuota{}}, // ResourceQuota
-			handler.EnqueueRequestsFromMapFunc(r.findObjects),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
-		).
-		Watches(&source.Kind{Type: &corev1.Secret{}}, // Secret
-			handler.EnqueueRequestsFromMapFunc(r.findObjects),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
-		).
-		Watches(&source.Kind{Type: &corev1.Service{}}, // Service
-			handler.EnqueueRequestsFromMapFunc(r.findObjects),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
-		).
-		Complete(r)
+		)
+	}
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+
+	r.controller = c
+	r.restMapper = mgr.GetRESTMapper()
+	r.watches = map[schema.GroupVersionKind]struct{}{}
+	return nil
 }
 
+// findObjects returns the ProjectResourceQuotas the namespace of the object belongs to currently,
+// the projects in the annotation might be stale, e.g. the namespace moved to another project.
 func (r *ProjectResourceQuotaReconciler) findObjects(obj client.Object) []reconcile.Request {
-	prqName, found := obj.GetAnnotations()[jentingiov1.ProjectResourceQuotaAnnotation]
-	if !found {
+	prqs, err := jentingiov1.GetProjectResourceQuotas(context.Background(), r.Client, obj.GetNamespace())
+	if err != nil {
+		return toRequests(sets.NewString(jentingiov1.ProjectResourceQuotaNames(obj)...))
+	}
+
+	names := sets.NewString()
+	for _, prq := range prqs {
+		names.Insert(prq.Name)
+	}
+	return toRequests(names)
+}
+
+// findProjectResourceQuotas returns the ProjectResourceQuotas which list the namespace in spec.namespaces or select namespaces by labels, and their ancestors.
+func (r *ProjectResourceQuotaReconciler) findProjectResourceQuotas(obj client.Object) []reconcile.Request {
+	prqList := &jentingiov1.ProjectResourceQuotaList{}
+	if err := r.Client.List(context.Background(), prqList); err != nil {
+		return nil
+	}
+
+	names := sets.NewString()
+	for _, prq := range prqList.Items {
+		// the namespace labels change might make the namespace join or leave the project and its ancestors
+		if prq.Spec.NamespaceSelector != nil || sets.NewString(prq.Spec.Namespaces...).Has(obj.GetName()) {
+			names.Insert(prq.Name)
+			for _, ancestor := range prq.Ancestors(prqList.Items) {
+				names.Insert(ancestor.Name)
+			}
+		}
+	}
+	return toRequests(names)
+}
+
+// findParentProjectResourceQuotas returns the ancestor ProjectResourceQuotas of the ProjectResourceQuota.
+func (r *ProjectResourceQuotaReconciler) findParentProjectResourceQuotas(obj client.Object) []reconcile.Request {
+	prq, ok := obj.(*jentingiov1.ProjectResourceQuota)
+	if !ok || len(prq.Spec.Parent) == 0 {
 		return nil
 	}
-	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: prqName}}}
+
+	prqList := &jentingiov1.ProjectResourceQuotaList{}
+	if err := r.Client.List(context.Background(), prqList); err != nil {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: prq.Spec.Parent}}}
+	}
+
+	names := sets.NewString(prq.Spec.Parent)
+	for _, ancestor := range prq.Ancestors(prqList.Items) {
+		names.Insert(ancestor.Name)
+	}
+	return toRequests(names)
+}
+
+// toRequests returns the reconcile requests of the ProjectResourceQuota names
+func toRequests(names sets.String) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, name := range names.List() {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+	}
+	return requests
+}
+
+// findNamespaceProjectResourceQuotas returns the ProjectResourceQuotas the namespace of the object belongs to and their ancestors.
+func (r *ProjectResourceQuotaReconciler) findNamespaceProjectResourceQuotas(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	prqList := &jentingiov1.ProjectResourceQuotaList{}
+	if err := r.Client.List(ctx, prqList); err != nil {
+		return nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: obj.GetNamespace()}, ns); err != nil {
+		return nil
+	}
+
+	names := sets.NewString()
+	for _, prq := range prqList.Items {
+		if matched, err := prq.MatchNamespace(ns); err == nil && matched {
+			names.Insert(prq.Name)
+			for _, ancestor := range prq.Ancestors(prqList.Items) {
+				names.Insert(ancestor.Name)
+			}
+		}
+	}
+	return toRequests(names)
 }
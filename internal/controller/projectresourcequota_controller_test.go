@@ -0,0 +1,382 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	jentingiov1 "github.com/jenting/projectresourcequota/api/v1"
+)
+
+var _ = Describe("ProjectResourceQuota controller", func() {
+	const objects = 5
+
+	It("should not lose the concurrent writes to the objects and the status", func() {
+		ctx := context.Background()
+		name := "concurrent-writers"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+
+		prq := &jentingiov1.ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: jentingiov1.ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("100")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		for i := 0; i < objects; i++ {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+			Expect(jentingiov1.AttributeTo(cm, prq.Name)).To(Succeed())
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+		}
+
+		r := &ProjectResourceQuotaReconciler{
+			Client:   k8sClient,
+			Scheme:   scheme.Scheme,
+			Recorder: record.NewFakeRecorder(1000),
+		}
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: prq.Name}}
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+
+		// remove the namespace from the project, the controller removes the annotation from the objects
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		prq.Spec.Namespaces = []string{name + "-other"}
+		Expect(k8sClient.Update(ctx, prq)).To(Succeed())
+
+		var wg sync.WaitGroup
+		// the other writer, e.g. the GitOps tools, updates the objects concurrently
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			for i := 0; i < objects; i++ {
+				Expect(retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+					cm := &corev1.ConfigMap{}
+					if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: name, Name: fmt.Sprintf("cm-%d", i)}, cm); err != nil {
+						return err
+					}
+					cm.Data = map[string]string{"writer": "gitops"}
+					return k8sClient.Update(ctx, cm)
+				})).To(Succeed())
+			}
+		}()
+		// the admission webhooks add the reservations concurrently
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			for i := 0; i < objects; i++ {
+				Expect(retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+					latest := &jentingiov1.ProjectResourceQuota{}
+					if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), latest); err != nil {
+						return err
+					}
+					latest.Status.Reservations = append(latest.Status.Reservations, jentingiov1.ProjectResourceQuotaReservation{
+						UID:               types.UID(fmt.Sprintf("%s-reservation-%d", name, i)),
+						Kind:              "ConfigMap",
+						Namespace:         name + "-other",
+						Name:              fmt.Sprintf("reservation-%d", i),
+						Usage:             corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")},
+						CreationTimestamp: metav1.Now(),
+					})
+					return k8sClient.Status().Update(ctx, latest)
+				})).To(Succeed())
+			}
+		}()
+		// the controller reconciles concurrently, the conflicts are retried
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			for i := 0; i < objects; i++ {
+				_, _ = r.Reconcile(ctx, req)
+			}
+		}()
+		wg.Wait()
+
+		Eventually(func() error {
+			_, err := r.Reconcile(ctx, req)
+			return err
+		}).Should(Succeed())
+
+		// the annotation removal and the other writes are both kept
+		cmList := &corev1.ConfigMapList{}
+		Expect(k8sClient.List(ctx, cmList, client.InNamespace(name))).To(Succeed())
+		Expect(cmList.Items).To(HaveLen(objects))
+		for _, cm := range cmList.Items {
+			Expect(jentingiov1.IsAttributedTo(&cm, prq.Name)).To(BeFalse())
+			Expect(cm.Data).To(HaveKeyWithValue("writer", "gitops"))
+		}
+
+		// the reservations added concurrently are kept and charged
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		Expect(prq.Status.Reservations).To(HaveLen(objects))
+		used := prq.Status.Used[corev1.ResourceConfigMaps]
+		Expect(used.Value()).To(BeEquivalentTo(objects))
+	})
+
+	It("should re-attribute the objects once the namespace moves to another project", func() {
+		ctx := context.Background()
+		name := "namespace-move"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+
+		from := &jentingiov1.ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-from"},
+			Spec: jentingiov1.ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("100")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, from)).To(Succeed())
+		to := &jentingiov1.ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-to"},
+			Spec: jentingiov1.ProjectResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("100")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, to)).To(Succeed())
+
+		for i := 0; i < objects; i++ {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+			Expect(jentingiov1.AttributeTo(cm, from.Name)).To(Succeed())
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+		}
+
+		// move the namespace without the former project reconciled
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(from), from)).To(Succeed())
+		from.Spec.Namespaces = nil
+		Expect(k8sClient.Update(ctx, from)).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(to), to)).To(Succeed())
+		to.Spec.Namespaces = []string{name}
+		Expect(k8sClient.Update(ctx, to)).To(Succeed())
+
+		r := &ProjectResourceQuotaReconciler{
+			Client:   k8sClient,
+			Scheme:   scheme.Scheme,
+			Recorder: record.NewFakeRecorder(1000),
+		}
+		Eventually(func() error {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: to.Name}})
+			return err
+		}).Should(Succeed())
+
+		// the objects are attributed to the project the namespace belongs to only
+		cmList := &corev1.ConfigMapList{}
+		Expect(k8sClient.List(ctx, cmList, client.InNamespace(name))).To(Succeed())
+		Expect(cmList.Items).To(HaveLen(objects))
+		for _, cm := range cmList.Items {
+			Expect(jentingiov1.ProjectResourceQuotaNames(&cm)).To(Equal([]string{to.Name}))
+		}
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(to), to)).To(Succeed())
+		used := to.Status.Used[corev1.ResourceConfigMaps]
+		Expect(used.Value()).To(BeEquivalentTo(objects))
+	})
+
+	It("should adopt the existing objects into the project over its hard limit", func() {
+		ctx := context.Background()
+		name := "adoption"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+
+		// the objects created before the project
+		for i := 0; i < objects; i++ {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+		}
+
+		prq := &jentingiov1.ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: jentingiov1.ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		r := &ProjectResourceQuotaReconciler{
+			Client:   k8sClient,
+			Scheme:   scheme.Scheme,
+			Recorder: record.NewFakeRecorder(1000),
+		}
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: prq.Name}})
+		Expect(err).NotTo(HaveOccurred())
+
+		// the objects are attributed and counted although they exceed the hard limit
+		cmList := &corev1.ConfigMapList{}
+		Expect(k8sClient.List(ctx, cmList, client.InNamespace(name))).To(Succeed())
+		Expect(cmList.Items).To(HaveLen(objects))
+		for _, cm := range cmList.Items {
+			Expect(jentingiov1.IsAttributedTo(&cm, prq.Name)).To(BeTrue())
+		}
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+		used := prq.Status.Used[corev1.ResourceConfigMaps]
+		Expect(used.Value()).To(BeEquivalentTo(objects))
+		Expect(meta.IsStatusConditionTrue(prq.Status.Conditions, jentingiov1.ConditionOverQuota)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(prq.Status.Conditions, jentingiov1.ConditionReady)).To(BeTrue())
+	})
+
+	It("should move the namespace in and out of the project by its labels", func() {
+		ctx := context.Background()
+		name := "namespace-relabel"
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"team": name}}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		prq := &jentingiov1.ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: jentingiov1.ProjectResourceQuotaSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": name}},
+				Hard:              corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("100")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		for i := 0; i < objects; i++ {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cm-%d", i), Namespace: name}}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+		}
+
+		r := &ProjectResourceQuotaReconciler{
+			Client:   k8sClient,
+			Scheme:   scheme.Scheme,
+			Recorder: record.NewFakeRecorder(1000),
+		}
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: prq.Name}}
+		// expectAttributed reconciles the project enqueued by the namespace and checks the objects within the project
+		expectAttributed := func(attributed bool) {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ns), ns)).To(Succeed())
+			Expect(r.findProjectResourceQuotas(ns)).To(ContainElement(req))
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+
+			cmList := &corev1.ConfigMapList{}
+			Expect(k8sClient.List(ctx, cmList, client.InNamespace(name))).To(Succeed())
+			Expect(cmList.Items).To(HaveLen(objects))
+			for _, cm := range cmList.Items {
+				Expect(jentingiov1.IsAttributedTo(&cm, prq.Name)).To(Equal(attributed))
+			}
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+			used := prq.Status.Used[corev1.ResourceConfigMaps]
+			if attributed {
+				Expect(prq.Status.Namespaces).To(HaveLen(1))
+				Expect(used.Value()).To(BeEquivalentTo(objects))
+			} else {
+				Expect(prq.Status.Namespaces).To(BeEmpty())
+				Expect(used.Value()).To(BeZero())
+			}
+		}
+
+		// the namespace selected by its labels
+		expectAttributed(true)
+
+		// the relabelled namespace leaves the project
+		ns.Labels = map[string]string{"team": name + "-other"}
+		Expect(k8sClient.Update(ctx, ns)).To(Succeed())
+		expectAttributed(false)
+
+		// the relabelled namespace joins the project again
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ns), ns)).To(Succeed())
+		ns.Labels = map[string]string{"team": name}
+		Expect(k8sClient.Update(ctx, ns)).To(Succeed())
+		expectAttributed(true)
+	})
+
+	It("should expire the reservation of the object never created in the quiet project", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		name := "reservation-expiry"
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+
+		prq := &jentingiov1.ProjectResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: jentingiov1.ProjectResourceQuotaSpec{
+				Namespaces: []string{name},
+				Hard:       corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("10")},
+			},
+		}
+		Expect(k8sClient.Create(ctx, prq)).To(Succeed())
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme.Scheme, MetricsBindAddress: "0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect((&ProjectResourceQuotaReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: record.NewFakeRecorder(1000),
+		}).SetupWithManager(mgr)).To(Succeed())
+		go func() {
+			defer GinkgoRecover()
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+
+		Eventually(func() bool {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+			return prq.Status.LastReconcileTime != nil
+		}).Should(BeTrue())
+
+		// the admission webhook reserves the usage of the object whose creation fails afterwards, e.g. AlreadyExists
+		Expect(retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq); err != nil {
+				return err
+			}
+			prq.Status.Reservations = append(prq.Status.Reservations, jentingiov1.ProjectResourceQuotaReservation{
+				UID:               types.UID(name),
+				Kind:              "ConfigMap",
+				Namespace:         name,
+				Name:              name,
+				Usage:             corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-jentingiov1.ReservationTimeout + 5*time.Second)),
+			})
+			return k8sClient.Status().Update(ctx, prq)
+		})).To(Succeed())
+
+		// the reservation is charged until it expires without any other event in the project
+		Eventually(func() int64 {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+			used := prq.Status.Used[corev1.ResourceConfigMaps]
+			return used.Value()
+		}).Should(BeEquivalentTo(1))
+		Eventually(func() []jentingiov1.ProjectResourceQuotaReservation {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(prq), prq)).To(Succeed())
+			return prq.Status.Reservations
+		}, 30*time.Second).Should(BeEmpty())
+		used := prq.Status.Used[corev1.ResourceConfigMaps]
+		Expect(used.Value()).To(BeZero())
+	})
+})
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics publishes the project resource quota metrics to the controller-runtime metrics registry,
+// which the manager exposes on the metrics endpoint.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	hard = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "projectresourcequota_hard",
+			Help: "The hard limit of the resource in the project resource quota",
+		},
+		[]string{"prq", "resource"},
+	)
+
+	used = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "projectresourcequota_used",
+			Help: "The used resource in the namespace of the project resource quota",
+		},
+		[]string{"prq", "resource", "namespace"},
+	)
+
+	admissionDenied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "projectresourcequota_admission_denied_total",
+			Help: "The number of the admission requests denied because the resource exceeds the project resource quota",
+		},
+		[]string{"prq", "resource", "kind"},
+	)
+
+	admissionViolations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "projectresourcequota_admission_violations_total",
+			Help: "The number of the admission requests admitted by the warn or dryrun enforcement action although the resource exceeds the project resource quota",
+		},
+		[]string{"prq", "resource", "kind", "enforcement_action"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(hard, used, admissionDenied, admissionViolations)
+}
+
+// SetHard records the hard limits of the project resource quota,
+// the resources no longer set are removed.
+func SetHard(prqName string, hardList corev1.ResourceList) {
+	hard.DeletePartialMatch(prometheus.Labels{"prq": prqName})
+	for resourceName, quantity := range hardList {
+		hard.WithLabelValues(prqName, string(resourceName)).Set(quantity.AsApproximateFloat64())
+	}
+}
+
+// SetUsed records the used resources of the namespaces within the project resource quota,
+// the namespaces no longer within the project are removed.
+func SetUsed(prqName string, namespaceUsed map[string]corev1.ResourceList) {
+	used.DeletePartialMatch(prometheus.Labels{"prq": prqName})
+	for namespace, usedList := range namespaceUsed {
+		for resourceName, quantity := range usedList {
+			used.WithLabelValues(prqName, string(resourceName), namespace).Set(quantity.AsApproximateFloat64())
+		}
+	}
+}
+
+// Delete removes the metrics of the deleted project resource quota
+func Delete(prqName string) {
+	hard.DeletePartialMatch(prometheus.Labels{"prq": prqName})
+	used.DeletePartialMatch(prometheus.Labels{"prq": prqName})
+	admissionDenied.DeletePartialMatch(prometheus.Labels{"prq": prqName})
+	admissionViolations.DeletePartialMatch(prometheus.Labels{"prq": prqName})
+}
+
+// RecordAdmissionDenied counts the admission request denied because the resource exceeds the project resource quota
+func RecordAdmissionDenied(prqName string, resourceName corev1.ResourceName, kind string) {
+	admissionDenied.WithLabelValues(prqName, string(resourceName), kind).Inc()
+}
+
+// RecordAdmissionViolation counts the admission request admitted by the warn or dryrun enforcement action
+// although the resource exceeds the project resource quota
+func RecordAdmissionViolation(prqName string, resourceName corev1.ResourceName, kind, enforcementAction string) {
+	admissionViolations.WithLabelValues(prqName, string(resourceName), kind, enforcementAction).Inc()
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Evaluator calculates the usage of the objects of a kind tracked by the project resource quotas,
+// the admission webhooks and the controller evaluate the objects with the same evaluators.
+type Evaluator interface {
+	// Kind returns the kind of the evaluated objects
+	Kind() string
+	// NewObject returns an empty object of the kind
+	NewObject() client.Object
+	// NewList returns an empty list of the kind
+	NewList() client.ObjectList
+	// Handles returns true if the resource name is tracked by the objects of the kind
+	Handles(resourceName corev1.ResourceName) bool
+	// Matches returns true if the object matches the scopes and the scope selector of the project resource quota
+	Matches(obj client.Object, scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) (bool, error)
+	// Usage returns the resource usage of the object
+	Usage(obj client.Object) corev1.ResourceList
+}
+
+// evaluators are the registered evaluators by kind
+var evaluators = map[string]Evaluator{}
+
+// Register registers the evaluator of the kind, so the kind is tracked by the project resource quotas
+func Register(evaluator Evaluator) {
+	if _, found := evaluators[evaluator.Kind()]; found {
+		panic(fmt.Sprintf("evaluator of kind %s is already registered", evaluator.Kind()))
+	}
+	evaluators[evaluator.Kind()] = evaluator
+}
+
+// EvaluatorFor returns the evaluator of the kind
+func EvaluatorFor(kind string) (Evaluator, bool) {
+	evaluator, found := evaluators[kind]
+	return evaluator, found
+}
+
+// Evaluators returns the registered evaluators sorted by kind
+func Evaluators() []Evaluator {
+	var list []Evaluator
+	for _, evaluator := range evaluators {
+		list = append(list, evaluator)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Kind() < list[j].Kind()
+	})
+	return list
+}
+
+// HandlesAny returns true if any resource name in the resource list is tracked by the objects of the evaluator kind
+func HandlesAny(evaluator Evaluator, rl corev1.ResourceList) bool {
+	for resourceName := range rl {
+		if evaluator.Handles(resourceName) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectCountEvaluator counts the objects of a kind, e.g. configmaps or secrets
+type objectCountEvaluator struct {
+	kind         string
+	resourceName corev1.ResourceName
+	newObject    func() client.Object
+	newList      func() client.ObjectList
+}
+
+func (e *objectCountEvaluator) Kind() string {
+	return e.kind
+}
+
+func (e *objectCountEvaluator) NewObject() client.Object {
+	return e.newObject()
+}
+
+func (e *objectCountEvaluator) NewList() client.ObjectList {
+	return e.newList()
+}
+
+func (e *objectCountEvaluator) Handles(resourceName corev1.ResourceName) bool {
+	return resourceName == e.resourceName
+}
+
+func (e *objectCountEvaluator) Matches(obj client.Object, scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) (bool, error) {
+	return true, nil
+}
+
+func (e *objectCountEvaluator) Usage(obj client.Object) corev1.ResourceList {
+	return corev1.ResourceList{e.resourceName: resource.MustParse("1")}
+}
+
+func init() {
+	Register(&objectCountEvaluator{
+		kind:         "ConfigMap",
+		resourceName: corev1.ResourceConfigMaps,
+		newObject:    func() client.Object { return &corev1.ConfigMap{} },
+		newList:      func() client.ObjectList { return &corev1.ConfigMapList{} },
+	})
+	Register(&objectCountEvaluator{
+		kind:         "ReplicationController",
+		resourceName: corev1.ResourceReplicationControllers,
+		newObject:    func() client.Object { return &corev1.ReplicationController{} },
+		newList:      func() client.ObjectList { return &corev1.ReplicationControllerList{} },
+	})
+	Register(&objectCountEvaluator{
+		kind:         "ResourceQuota",
+		resourceName: corev1.ResourceQuotas,
+		newObject:    func() client.Object { return &corev1.ResourceQuota{} },
+		newList:      func() client.ObjectList { return &corev1.ResourceQuotaList{} },
+	})
+	Register(&objectCountEvaluator{
+		kind:         "Secret",
+		resourceName: corev1.ResourceSecrets,
+		newObject:    func() client.Object { return &corev1.Secret{} },
+		newList:      func() client.ObjectList { return &corev1.SecretList{} },
+	})
+	Register(&podEvaluator{})
+	Register(&persistentVolumeClaimEvaluator{})
+	Register(&serviceEvaluator{})
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newPod() *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:              resource.MustParse("100m"),
+							corev1.ResourceMemory:           resource.MustParse("64Mi"),
+							corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:              resource.MustParse("200m"),
+							corev1.ResourceMemory:           resource.MustParse("128Mi"),
+							corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+						},
+					},
+				},
+				{
+					Name: "sidecar",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("50m"),
+							corev1.ResourceMemory: resource.MustParse("32Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("100m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newPersistentVolumeClaim() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		},
+	}
+}
+
+func newService(serviceType corev1.ServiceType) *corev1.Service {
+	return &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type: serviceType,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80},
+				{Name: "https", Port: 443, NodePort: 30443},
+			},
+		},
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		name         string
+		kind         string
+		obj          client.Object
+		resourceName corev1.ResourceName
+		want         string
+	}{
+		{name: "pod cpu", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceCPU, want: "150m"},
+		{name: "pod memory", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceMemory, want: "96Mi"},
+		{name: "pod ephemeral-storage", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceEphemeralStorage, want: "1Gi"},
+		{name: "pod count", kind: "Pod", obj: newPod(), resourceName: corev1.ResourcePods, want: "1"},
+		{name: "pod requests.cpu", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceRequestsCPU, want: "150m"},
+		{name: "pod requests.memory", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceRequestsMemory, want: "96Mi"},
+		{name: "pod requests.ephemeral-storage", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceRequestsEphemeralStorage, want: "1Gi"},
+		{name: "pod limits.cpu", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceLimitsCPU, want: "300m"},
+		{name: "pod limits.memory", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceLimitsMemory, want: "192Mi"},
+		{name: "pod limits.ephemeral-storage", kind: "Pod", obj: newPod(), resourceName: corev1.ResourceLimitsEphemeralStorage, want: "2Gi"},
+		{name: "persistentvolumeclaim storage", kind: "PersistentVolumeClaim", obj: newPersistentVolumeClaim(), resourceName: corev1.ResourceStorage, want: "10Gi"},
+		{name: "persistentvolumeclaim requests.storage", kind: "PersistentVolumeClaim", obj: newPersistentVolumeClaim(), resourceName: corev1.ResourceRequestsStorage, want: "10Gi"},
+		{name: "persistentvolumeclaim count", kind: "PersistentVolumeClaim", obj: newPersistentVolumeClaim(), resourceName: corev1.ResourcePersistentVolumeClaims, want: "1"},
+		{name: "service count", kind: "Service", obj: newService(corev1.ServiceTypeClusterIP), resourceName: corev1.ResourceServices, want: "1"},
+		{name: "service nodeports", kind: "Service", obj: newService(corev1.ServiceTypeNodePort), resourceName: corev1.ResourceServicesNodePorts, want: "2"},
+		{name: "service loadbalancer", kind: "Service", obj: newService(corev1.ServiceTypeLoadBalancer), resourceName: corev1.ResourceServicesLoadBalancers, want: "1"},
+		{name: "configmap count", kind: "ConfigMap", obj: &corev1.ConfigMap{}, resourceName: corev1.ResourceConfigMaps, want: "1"},
+		{name: "replicationcontroller count", kind: "ReplicationController", obj: &corev1.ReplicationController{}, resourceName: corev1.ResourceReplicationControllers, want: "1"},
+		{name: "resourcequota count", kind: "ResourceQuota", obj: &corev1.ResourceQuota{}, resourceName: corev1.ResourceQuotas, want: "1"},
+		{name: "secret count", kind: "Secret", obj: &corev1.Secret{}, resourceName: corev1.ResourceSecrets, want: "1"},
+	}
+
+	covered := map[corev1.ResourceName]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := usage(t, tt.kind, tt.obj)[tt.resourceName]
+			if !found {
+				t.Fatalf("Usage() of %s has no %s", tt.kind, tt.resourceName)
+			}
+			if want := resource.MustParse(tt.want); got.Cmp(want) != 0 {
+				t.Errorf("Usage()[%s] = %s, want %s", tt.resourceName, got.String(), want.String())
+			}
+		})
+		covered[tt.resourceName] = true
+	}
+
+	// every supported resource name is calculated by an evaluator
+	for _, resourceName := range ResourceNames {
+		if !covered[resourceName] {
+			t.Errorf("resource name %s is not covered", resourceName)
+		}
+	}
+}
+
+func TestUsageServiceType(t *testing.T) {
+	allocateLoadBalancerNodePorts := false
+	tests := []struct {
+		name string
+		svc  *corev1.Service
+		want corev1.ResourceList
+	}{
+		{
+			name: "cluster ip",
+			svc:  newService(corev1.ServiceTypeClusterIP),
+			want: corev1.ResourceList{corev1.ResourceServices: resource.MustParse("1")},
+		},
+		{
+			name: "node port",
+			svc:  newService(corev1.ServiceTypeNodePort),
+			want: corev1.ResourceList{
+				corev1.ResourceServices:          resource.MustParse("1"),
+				corev1.ResourceServicesNodePorts: resource.MustParse("2"),
+			},
+		},
+		{
+			name: "load balancer",
+			svc:  newService(corev1.ServiceTypeLoadBalancer),
+			want: corev1.ResourceList{
+				corev1.ResourceServices:              resource.MustParse("1"),
+				corev1.ResourceServicesLoadBalancers: resource.MustParse("1"),
+				corev1.ResourceServicesNodePorts:     resource.MustParse("2"),
+			},
+		},
+		{
+			name: "load balancer without node ports",
+			svc: func() *corev1.Service {
+				svc := newService(corev1.ServiceTypeLoadBalancer)
+				svc.Spec.AllocateLoadBalancerNodePorts = &allocateLoadBalancerNodePorts
+				return svc
+			}(),
+			want: corev1.ResourceList{
+				corev1.ResourceServices:              resource.MustParse("1"),
+				corev1.ResourceServicesLoadBalancers: resource.MustParse("1"),
+				corev1.ResourceServicesNodePorts:     resource.MustParse("1"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResourceList(t, usage(t, "Service", tt.svc), tt.want)
+		})
+	}
+}
+
+func TestUsageStorageClass(t *testing.T) {
+	pvc := newPersistentVolumeClaim()
+	storageClass := "gold"
+	pvc.Spec.StorageClassName = &storageClass
+
+	assertResourceList(t, usage(t, "PersistentVolumeClaim", pvc), corev1.ResourceList{
+		corev1.ResourcePersistentVolumeClaims: resource.MustParse("1"),
+		corev1.ResourceStorage:                resource.MustParse("10Gi"),
+		corev1.ResourceRequestsStorage:        resource.MustParse("10Gi"),
+		V1ResourceByStorageClass(storageClass, corev1.ResourcePersistentVolumeClaims): resource.MustParse("1"),
+		V1ResourceByStorageClass(storageClass, corev1.ResourceRequestsStorage):        resource.MustParse("10Gi"),
+	})
+}
+
+func TestEvaluatorForNotTracked(t *testing.T) {
+	if _, found := EvaluatorFor("Endpoints"); found {
+		t.Errorf("EvaluatorFor(Endpoints) is found, want not tracked")
+	}
+}
+
+func TestEvaluatorsHandleUsage(t *testing.T) {
+	objects := map[string]client.Object{
+		"Pod":                   newPod(),
+		"PersistentVolumeClaim": newPersistentVolumeClaim(),
+		"Service":               newService(corev1.ServiceTypeNodePort),
+		"ConfigMap":             &corev1.ConfigMap{},
+		"ReplicationController": &corev1.ReplicationController{},
+		"ResourceQuota":         &corev1.ResourceQuota{},
+		"Secret":                &corev1.Secret{},
+	}
+
+	// the usage of every kind is tracked by its evaluator only
+	for kind, obj := range objects {
+		for resourceName := range usage(t, kind, obj) {
+			var handledBy []string
+			for _, evaluator := range Evaluators() {
+				if evaluator.Handles(resourceName) {
+					handledBy = append(handledBy, evaluator.Kind())
+				}
+			}
+			if len(handledBy) != 1 || handledBy[0] != kind {
+				t.Errorf("resource name %s of %s is handled by %v, want the %s evaluator only", resourceName, kind, handledBy, kind)
+			}
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		newUsage corev1.ResourceList
+		oldUsage corev1.ResourceList
+		want     corev1.ResourceList
+	}{
+		{
+			name:     "increased",
+			newUsage: corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("20Gi")},
+			oldUsage: corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("10Gi")},
+			want:     corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("10Gi")},
+		},
+		{
+			name:     "decreased",
+			newUsage: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("100m")},
+			oldUsage: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("200m")},
+			want:     corev1.ResourceList{},
+		},
+		{
+			name:     "added",
+			newUsage: corev1.ResourceList{corev1.ResourceServices: resource.MustParse("1"), corev1.ResourceServicesLoadBalancers: resource.MustParse("1")},
+			oldUsage: corev1.ResourceList{corev1.ResourceServices: resource.MustParse("1")},
+			want:     corev1.ResourceList{corev1.ResourceServicesLoadBalancers: resource.MustParse("1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResourceList(t, Delta(tt.newUsage, tt.oldUsage), tt.want)
+		})
+	}
+}
+
+// usage returns the resource usage of the object calculated by the evaluator of the kind
+func usage(t *testing.T, kind string, obj client.Object) corev1.ResourceList {
+	t.Helper()
+	evaluator, found := EvaluatorFor(kind)
+	if !found {
+		t.Fatalf("evaluator of kind %s is not registered", kind)
+	}
+	return evaluator.Usage(obj)
+}
+
+// assertResourceList asserts the resource lists have the same resources and quantities
+func assertResourceList(t *testing.T, got, want corev1.ResourceList) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for resourceName, wantQuantity := range want {
+		gotQuantity, found := got[resourceName]
+		if !found || gotQuantity.Cmp(wantQuantity) != 0 {
+			t.Errorf("%s = %s, want %s", resourceName, gotQuantity.String(), wantQuantity.String())
+		}
+	}
+}
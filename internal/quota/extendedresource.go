@@ -0,0 +1,61 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// nativeResourceDomain is the domain of the resource names defined by the Kubernetes
+const nativeResourceDomain = "kubernetes.io/"
+
+// IsExtendedResourceName returns true if the resource name is an extended resource, e.g. nvidia.com/gpu.
+// The extended resource name is a fully-qualified name outside the kubernetes.io domain.
+func IsExtendedResourceName(resourceName corev1.ResourceName) bool {
+	name := string(resourceName)
+	if !strings.Contains(name, "/") || strings.Contains(name, nativeResourceDomain) {
+		return false
+	}
+	if strings.HasPrefix(name, corev1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	return len(validation.IsQualifiedName(name)) == 0
+}
+
+// IsExtendedResourceRequests returns true if the resource name is the requests.<extended-resource-name> resource,
+// the extended resources cannot be overcommitted so the quota is on the requests only.
+func IsExtendedResourceRequests(resourceName corev1.ResourceName) bool {
+	name := string(resourceName)
+	if !strings.HasPrefix(name, corev1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	return IsExtendedResourceName(corev1.ResourceName(strings.TrimPrefix(name, corev1.DefaultResourceRequestsPrefix)))
+}
+
+// ExtendedResourceRequests returns the requests.<extended-resource-name> usage of the resource requests
+func ExtendedResourceRequests(reqs corev1.ResourceList) corev1.ResourceList {
+	usage := corev1.ResourceList{}
+	for resourceName, quantity := range reqs {
+		if IsExtendedResourceName(resourceName) {
+			usage[corev1.ResourceName(corev1.DefaultResourceRequestsPrefix+string(resourceName))] = quantity.DeepCopy()
+		}
+	}
+	return usage
+}
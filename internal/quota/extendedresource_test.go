@@ -0,0 +1,89 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestIsExtendedResourceName(t *testing.T) {
+	tests := []struct {
+		resourceName corev1.ResourceName
+		want         bool
+	}{
+		{resourceName: "nvidia.com/gpu", want: true},
+		{resourceName: "example.com/foo", want: true},
+		{resourceName: corev1.ResourceCPU, want: false},
+		{resourceName: "kubernetes.io/foo", want: false},
+		{resourceName: "hugepages.kubernetes.io/foo", want: false},
+		{resourceName: "requests.nvidia.com/gpu", want: false},
+		{resourceName: "nvidia.com/", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.resourceName), func(t *testing.T) {
+			if got := IsExtendedResourceName(tt.resourceName); got != tt.want {
+				t.Errorf("IsExtendedResourceName(%s) = %v, want %v", tt.resourceName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsExtendedResourceRequests(t *testing.T) {
+	tests := []struct {
+		resourceName corev1.ResourceName
+		want         bool
+	}{
+		{resourceName: "requests.nvidia.com/gpu", want: true},
+		{resourceName: "nvidia.com/gpu", want: false},
+		{resourceName: corev1.ResourceRequestsCPU, want: false},
+		{resourceName: "requests.kubernetes.io/foo", want: false},
+		{resourceName: "requests.requests.nvidia.com/gpu", want: false},
+		// the extended resources cannot be overcommitted, the limits are not tracked
+		{resourceName: "limits.nvidia.com/gpu", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.resourceName), func(t *testing.T) {
+			if got := IsExtendedResourceRequests(tt.resourceName); got != tt.want {
+				t.Errorf("IsExtendedResourceRequests(%s) = %v, want %v", tt.resourceName, got, tt.want)
+			}
+		})
+	}
+
+	// the pods track the requests.<extended-resource-name> only
+	if !IsPodResource("requests.nvidia.com/gpu") || IsPodResource("limits.nvidia.com/gpu") {
+		t.Errorf("IsPodResource() tracks the extended resource limits or not the requests")
+	}
+}
+
+func TestExtendedResourceRequests(t *testing.T) {
+	reqs := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+		"kubernetes.io/foo":   resource.MustParse("1"),
+		"nvidia.com/gpu":      resource.MustParse("2"),
+		"example.com/foo":     resource.MustParse("1"),
+	}
+	assertResourceList(t, ExtendedResourceRequests(reqs), corev1.ResourceList{
+		"requests.nvidia.com/gpu":  resource.MustParse("2"),
+		"requests.example.com/foo": resource.MustParse("1"),
+	})
+}
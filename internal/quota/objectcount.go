@@ -0,0 +1,54 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// objectCountPrefix is the prefix of the count/<resource>.<group> resource names
+const objectCountPrefix = "count/"
+
+// ObjectCountQuotaResourceNameFor returns the count/<resource>.<group> resource name of the group resource
+func ObjectCountQuotaResourceNameFor(groupResource schema.GroupResource) corev1.ResourceName {
+	if len(groupResource.Group) == 0 {
+		return corev1.ResourceName(objectCountPrefix + groupResource.Resource)
+	}
+	return corev1.ResourceName(objectCountPrefix + groupResource.Resource + "." + groupResource.Group)
+}
+
+// IsObjectCountResource returns true if the resource name is a count/<resource>.<group> resource name
+func IsObjectCountResource(resourceName corev1.ResourceName) bool {
+	return strings.HasPrefix(string(resourceName), objectCountPrefix)
+}
+
+// ObjectCountGroupResource returns the group resource of the count/<resource>.<group> resource name,
+// the resource in the core group has no group suffix, e.g. count/pods.
+func ObjectCountGroupResource(resourceName corev1.ResourceName) (schema.GroupResource, bool) {
+	if !IsObjectCountResource(resourceName) {
+		return schema.GroupResource{}, false
+	}
+
+	resource, group, _ := strings.Cut(strings.TrimPrefix(string(resourceName), objectCountPrefix), ".")
+	if len(resource) == 0 {
+		return schema.GroupResource{}, false
+	}
+	return schema.GroupResource{Group: group, Resource: resource}, true
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestObjectCountGroupResource(t *testing.T) {
+	tests := []struct {
+		resourceName corev1.ResourceName
+		want         schema.GroupResource
+		wantFound    bool
+	}{
+		{resourceName: "count/configmaps", want: schema.GroupResource{Resource: "configmaps"}, wantFound: true},
+		{resourceName: "count/deployments.apps", want: schema.GroupResource{Group: "apps", Resource: "deployments"}, wantFound: true},
+		{resourceName: "count/widgets.example.com", want: schema.GroupResource{Group: "example.com", Resource: "widgets"}, wantFound: true},
+		{resourceName: "count/", wantFound: false},
+		{resourceName: "count/.apps", wantFound: false},
+		{resourceName: corev1.ResourceConfigMaps, wantFound: false},
+		{resourceName: corev1.ResourceRequestsCPU, wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.resourceName), func(t *testing.T) {
+			got, found := ObjectCountGroupResource(tt.resourceName)
+			if found != tt.wantFound || got != tt.want {
+				t.Errorf("ObjectCountGroupResource(%s) = %v, %v, want %v, %v", tt.resourceName, got, found, tt.want, tt.wantFound)
+			}
+			// the group resource round-trips to the resource name
+			if found && ObjectCountQuotaResourceNameFor(got) != tt.resourceName {
+				t.Errorf("ObjectCountQuotaResourceNameFor(%v) = %s, want %s", got, ObjectCountQuotaResourceNameFor(got), tt.resourceName)
+			}
+		})
+	}
+}
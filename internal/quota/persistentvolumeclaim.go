@@ -0,0 +1,120 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// storageClassSuffix is the suffix of the resource name tracked per storage class,
+// i.e. <storage-class-name>.storageclass.storage.k8s.io/<resource-name>
+const storageClassSuffix = ".storageclass.storage.k8s.io/"
+
+// betaStorageClassAnnotation is the deprecated annotation of the PersistentVolumeClaim storage class,
+// it takes precedence over spec.storageClassName as the Kubernetes does.
+const betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+// V1ResourceByStorageClass returns the resource name tracked per storage class
+func V1ResourceByStorageClass(storageClass string, resourceName corev1.ResourceName) corev1.ResourceName {
+	return corev1.ResourceName(storageClass + storageClassSuffix + string(resourceName))
+}
+
+// IsStorageClassResource returns true if the resource name is tracked per storage class
+func IsStorageClassResource(resourceName corev1.ResourceName) bool {
+	storageClass, name, found := strings.Cut(string(resourceName), storageClassSuffix)
+	if !found || storageClass == "" {
+		return false
+	}
+	return name == string(corev1.ResourcePersistentVolumeClaims) || name == string(corev1.ResourceRequestsStorage)
+}
+
+// IsPersistentVolumeClaimResource returns true if the resource name is tracked by the PersistentVolumeClaims
+func IsPersistentVolumeClaimResource(resourceName corev1.ResourceName) bool {
+	switch resourceName {
+	case corev1.ResourcePersistentVolumeClaims, corev1.ResourceRequestsStorage, corev1.ResourceStorage:
+		return true
+	}
+	return IsStorageClassResource(resourceName)
+}
+
+// PersistentVolumeClaimClass returns the storage class name of the PersistentVolumeClaim
+func PersistentVolumeClaimClass(pvc *corev1.PersistentVolumeClaim) string {
+	if class, found := pvc.Annotations[betaStorageClassAnnotation]; found {
+		return class
+	}
+	if pvc.Spec.StorageClassName != nil {
+		return *pvc.Spec.StorageClassName
+	}
+	return ""
+}
+
+// PersistentVolumeClaimUsage returns the resource usage of the PersistentVolumeClaim,
+// the total and per storage class count and storage requests.
+func PersistentVolumeClaimUsage(pvc *corev1.PersistentVolumeClaim) corev1.ResourceList {
+	usage := corev1.ResourceList{
+		corev1.ResourcePersistentVolumeClaims: resource.MustParse("1"),
+	}
+
+	storage, found := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if found {
+		usage[corev1.ResourceRequestsStorage] = storage.DeepCopy()
+		usage[corev1.ResourceStorage] = storage.DeepCopy()
+	}
+
+	if class := PersistentVolumeClaimClass(pvc); class != "" {
+		usage[V1ResourceByStorageClass(class, corev1.ResourcePersistentVolumeClaims)] = resource.MustParse("1")
+		if found {
+			usage[V1ResourceByStorageClass(class, corev1.ResourceRequestsStorage)] = storage.DeepCopy()
+		}
+	}
+	return usage
+}
+
+// persistentVolumeClaimEvaluator evaluates the PersistentVolumeClaims
+type persistentVolumeClaimEvaluator struct{}
+
+func (e *persistentVolumeClaimEvaluator) Kind() string {
+	return "PersistentVolumeClaim"
+}
+
+func (e *persistentVolumeClaimEvaluator) NewObject() client.Object {
+	return &corev1.PersistentVolumeClaim{}
+}
+
+func (e *persistentVolumeClaimEvaluator) NewList() client.ObjectList {
+	return &corev1.PersistentVolumeClaimList{}
+}
+
+func (e *persistentVolumeClaimEvaluator) Handles(resourceName corev1.ResourceName) bool {
+	return IsPersistentVolumeClaimResource(resourceName)
+}
+
+func (e *persistentVolumeClaimEvaluator) Matches(obj client.Object, scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) (bool, error) {
+	return true, nil
+}
+
+func (e *persistentVolumeClaimEvaluator) Usage(obj client.Object) corev1.ResourceList {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return corev1.ResourceList{}
+	}
+	return PersistentVolumeClaimUsage(pvc)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota calculates the resource usage of the objects tracked by the project resource quotas,
+// following the Kubernetes resource quota evaluators semantics.
+package quota
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// QuotaV1Pod returns true if the pod is eligible to track against a quota.
+// The pod in a terminal state, or the pod marked for deletion whose grace period has passed, is not tracked.
+func QuotaV1Pod(pod *corev1.Pod, now time.Time) bool {
+	if IsTerminal(pod) {
+		return false
+	}
+
+	// the pod marked for deletion is not tracked once its grace period has passed,
+	// because the kubelet is going to kill it.
+	if expiration, ok := DeletionGracePeriodExpiration(pod); ok && now.After(expiration) {
+		return false
+	}
+	return true
+}
+
+// IsTerminal returns true if the pod is in a terminal state
+func IsTerminal(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded
+}
+
+// DeletionGracePeriodExpiration returns the time the pod deletion grace period passes,
+// or false if the pod is not marked for deletion.
+func DeletionGracePeriodExpiration(pod *corev1.Pod) (time.Time, bool) {
+	if pod.DeletionTimestamp == nil || pod.DeletionGracePeriodSeconds == nil {
+		return time.Time{}, false
+	}
+	return pod.DeletionTimestamp.Time.Add(time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second), true
+}
+
+// IsPodResource returns true if the resource name is tracked by the Pods
+func IsPodResource(resourceName corev1.ResourceName) bool {
+	switch resourceName {
+	case corev1.ResourcePods,
+		corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage,
+		corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory, corev1.ResourceRequestsEphemeralStorage,
+		corev1.ResourceLimitsCPU, corev1.ResourceLimitsMemory, corev1.ResourceLimitsEphemeralStorage:
+		return true
+	}
+	return IsExtendedResourceRequests(resourceName)
+}
+
+// PodRequestsAndLimits returns the effective resource requests and limits of the pod.
+// It follows the kubelet and scheduler rules: the larger of the sum of the app containers
+// and the maximum of any init container, plus the pod overhead set by the RuntimeClass.
+//
+// The restartable init containers (sidecars) are not distinguishable with the vendored k8s.io/api,
+// they are accounted as the regular init containers.
+func PodRequestsAndLimits(pod *corev1.Pod) (reqs, limits corev1.ResourceList) {
+	reqs, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResourceList(reqs, container.Resources.Requests)
+		addResourceList(limits, container.Resources.Limits)
+	}
+
+	// the init containers run sequentially before the app containers,
+	// the effective value is the larger of the app containers sum and any init container
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(reqs, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+
+	// add the pod overhead to the requests, and to the limits if the limits are set
+	if pod.Spec.Overhead != nil {
+		addResourceList(reqs, pod.Spec.Overhead)
+		for name, quantity := range pod.Spec.Overhead {
+			if value, found := limits[name]; found {
+				value.Add(quantity)
+				limits[name] = value
+			}
+		}
+	}
+	return reqs, limits
+}
+
+// PodUsage returns the resource usage of the pod, the count, the effective resource requests and limits,
+// and the extended resource requests.
+func PodUsage(pod *corev1.Pod) corev1.ResourceList {
+	reqs, limits := PodRequestsAndLimits(pod)
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:                     resource.MustParse("1"),
+		corev1.ResourceCPU:                      reqs[corev1.ResourceCPU],
+		corev1.ResourceMemory:                   reqs[corev1.ResourceMemory],
+		corev1.ResourceEphemeralStorage:         reqs[corev1.ResourceEphemeralStorage],
+		corev1.ResourceRequestsCPU:              reqs[corev1.ResourceCPU],
+		corev1.ResourceRequestsMemory:           reqs[corev1.ResourceMemory],
+		corev1.ResourceRequestsEphemeralStorage: reqs[corev1.ResourceEphemeralStorage],
+		corev1.ResourceLimitsCPU:                limits[corev1.ResourceCPU],
+		corev1.ResourceLimitsMemory:             limits[corev1.ResourceMemory],
+		corev1.ResourceLimitsEphemeralStorage:   limits[corev1.ResourceEphemeralStorage],
+	}
+	// the requests.<extended-resource-name> usage, e.g. requests.nvidia.com/gpu
+	for resourceName, quantity := range ExtendedResourceRequests(reqs) {
+		usage[resourceName] = quantity
+	}
+	return usage
+}
+
+// podEvaluator evaluates the Pods
+type podEvaluator struct{}
+
+func (e *podEvaluator) Kind() string {
+	return "Pod"
+}
+
+func (e *podEvaluator) NewObject() client.Object {
+	return &corev1.Pod{}
+}
+
+func (e *podEvaluator) NewList() client.ObjectList {
+	return &corev1.PodList{}
+}
+
+func (e *podEvaluator) Handles(resourceName corev1.ResourceName) bool {
+	return IsPodResource(resourceName)
+}
+
+func (e *podEvaluator) Matches(obj client.Object, scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) (bool, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return false, fmt.Errorf("expected a Pod but got a %T", obj)
+	}
+	return PodMatchesScopes(pod, scopes, selector)
+}
+
+func (e *podEvaluator) Usage(obj client.Object) corev1.ResourceList {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return corev1.ResourceList{}
+	}
+	return PodUsage(pod)
+}
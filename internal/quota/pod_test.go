@@ -0,0 +1,203 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodRequestsAndLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(pod *corev1.Pod)
+		wantReqs   corev1.ResourceList
+		wantLimits corev1.ResourceList
+	}{
+		{
+			name:   "app containers sum",
+			mutate: func(pod *corev1.Pod) {},
+			wantReqs: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("150m"),
+				corev1.ResourceMemory:           resource.MustParse("96Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			},
+			wantLimits: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("300m"),
+				corev1.ResourceMemory:           resource.MustParse("192Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+			},
+		},
+		{
+			name: "init container less than app containers sum",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.InitContainers = []corev1.Container{{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+						Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+					},
+				}}
+			},
+			wantReqs: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("150m"),
+				corev1.ResourceMemory:           resource.MustParse("96Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			},
+			wantLimits: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("300m"),
+				corev1.ResourceMemory:           resource.MustParse("192Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+			},
+		},
+		{
+			name: "init container greater than app containers sum",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.InitContainers = []corev1.Container{
+					{
+						Name: "init-cpu",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+							Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+						},
+					},
+					{
+						Name: "init-memory",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+							Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+						},
+					},
+				}
+			},
+			wantReqs: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("500m"),
+				corev1.ResourceMemory:           resource.MustParse("256Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			},
+			wantLimits: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("1"),
+				corev1.ResourceMemory:           resource.MustParse("512Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+			},
+		},
+		{
+			name: "overhead added to requests and limits",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("16Mi")}
+			},
+			wantReqs: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("160m"),
+				corev1.ResourceMemory:           resource.MustParse("112Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			},
+			wantLimits: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("310m"),
+				corev1.ResourceMemory:           resource.MustParse("208Mi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+			},
+		},
+		{
+			name: "overhead not added to limits not set",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers = []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+						Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+					},
+				}}
+				pod.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m"), corev1.ResourceMemory: resource.MustParse("16Mi")}
+			},
+			wantReqs: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("110m"),
+				corev1.ResourceMemory: resource.MustParse("16Mi"),
+			},
+			wantLimits: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("210m"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := newPod()
+			tt.mutate(pod)
+			reqs, limits := PodRequestsAndLimits(pod)
+			assertResourceList(t, reqs, tt.wantReqs)
+			assertResourceList(t, limits, tt.wantLimits)
+		})
+	}
+}
+
+func TestQuotaV1Pod(t *testing.T) {
+	now := time.Now()
+	gracePeriodSeconds := int64(30)
+	tests := []struct {
+		name   string
+		mutate func(pod *corev1.Pod)
+		want   bool
+	}{
+		{
+			name:   "running",
+			mutate: func(pod *corev1.Pod) { pod.Status.Phase = corev1.PodRunning },
+			want:   true,
+		},
+		{
+			name:   "succeeded",
+			mutate: func(pod *corev1.Pod) { pod.Status.Phase = corev1.PodSucceeded },
+			want:   false,
+		},
+		{
+			name:   "failed",
+			mutate: func(pod *corev1.Pod) { pod.Status.Phase = corev1.PodFailed },
+			want:   false,
+		},
+		{
+			name: "marked for deletion within the grace period",
+			mutate: func(pod *corev1.Pod) {
+				pod.Status.Phase = corev1.PodRunning
+				pod.DeletionTimestamp = &metav1.Time{Time: now.Add(-10 * time.Second)}
+				pod.DeletionGracePeriodSeconds = &gracePeriodSeconds
+			},
+			want: true,
+		},
+		{
+			name: "marked for deletion after the grace period",
+			mutate: func(pod *corev1.Pod) {
+				pod.Status.Phase = corev1.PodRunning
+				pod.DeletionTimestamp = &metav1.Time{Time: now.Add(-time.Minute)}
+				pod.DeletionGracePeriodSeconds = &gracePeriodSeconds
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := newPod()
+			tt.mutate(pod)
+			if got := QuotaV1Pod(pod, now); got != tt.want {
+				t.Errorf("QuotaV1Pod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
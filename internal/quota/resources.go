@@ -0,0 +1,91 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ResourceNames are the standard resource names supported by the project resource quotas
+var ResourceNames = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+	corev1.ResourceStorage,
+	corev1.ResourceEphemeralStorage,
+	corev1.ResourcePods,
+	corev1.ResourceServices,
+	corev1.ResourceReplicationControllers,
+	corev1.ResourceQuotas,
+	corev1.ResourceSecrets,
+	corev1.ResourceConfigMaps,
+	corev1.ResourcePersistentVolumeClaims,
+	corev1.ResourceServicesNodePorts,
+	corev1.ResourceServicesLoadBalancers,
+	corev1.ResourceRequestsCPU,
+	corev1.ResourceRequestsMemory,
+	corev1.ResourceRequestsStorage,
+	corev1.ResourceRequestsEphemeralStorage,
+	corev1.ResourceLimitsCPU,
+	corev1.ResourceLimitsMemory,
+	corev1.ResourceLimitsEphemeralStorage,
+}
+
+// IsStandardResource returns true if the resource name is one of the standard resource names
+func IsStandardResource(resourceName corev1.ResourceName) bool {
+	for _, name := range ResourceNames {
+		if name == resourceName {
+			return true
+		}
+	}
+	return false
+}
+
+// Delta returns the increased resources from oldUsage to newUsage, the decreased resources are omitted
+func Delta(newUsage, oldUsage corev1.ResourceList) corev1.ResourceList {
+	delta := corev1.ResourceList{}
+	for name, quantity := range newUsage {
+		increased := quantity.DeepCopy()
+		if old, found := oldUsage[name]; found {
+			increased.Sub(old)
+		}
+		if increased.Sign() > 0 {
+			delta[name] = increased
+		}
+	}
+	return delta
+}
+
+// addResourceList adds the resources in newList to list
+func addResourceList(list, newList corev1.ResourceList) {
+	for name, quantity := range newList {
+		if value, found := list[name]; !found {
+			list[name] = quantity.DeepCopy()
+		} else {
+			value.Add(quantity)
+			list[name] = value
+		}
+	}
+}
+
+// maxResourceList sets list to the greater of list/newList for every resource in newList
+func maxResourceList(list, newList corev1.ResourceList) {
+	for name, quantity := range newList {
+		if value, found := list[name]; !found || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ScopeRequirements returns the scopes and the scope selector as the scope selector requirements,
+// a scope is equivalent to the requirement with the Exists operator.
+func ScopeRequirements(scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) []corev1.ScopedResourceSelectorRequirement {
+	var requirements []corev1.ScopedResourceSelectorRequirement
+	for _, scope := range scopes {
+		requirements = append(requirements, corev1.ScopedResourceSelectorRequirement{
+			ScopeName: scope,
+			Operator:  corev1.ScopeSelectorOpExists,
+		})
+	}
+	if selector != nil {
+		requirements = append(requirements, selector.MatchExpressions...)
+	}
+	return requirements
+}
+
+// PodMatchesScopes returns true if the pod matches all the scopes and the scope selector requirements
+func PodMatchesScopes(pod *corev1.Pod, scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) (bool, error) {
+	for _, requirement := range ScopeRequirements(scopes, selector) {
+		matched, err := podMatchesScopeRequirement(pod, requirement)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// podMatchesScopeRequirement returns true if the pod matches the scope selector requirement
+func podMatchesScopeRequirement(pod *corev1.Pod, requirement corev1.ScopedResourceSelectorRequirement) (bool, error) {
+	switch requirement.ScopeName {
+	case corev1.ResourceQuotaScopeTerminating:
+		return isTerminating(pod) == (requirement.Operator == corev1.ScopeSelectorOpExists), nil
+	case corev1.ResourceQuotaScopeNotTerminating:
+		return !isTerminating(pod) == (requirement.Operator == corev1.ScopeSelectorOpExists), nil
+	case corev1.ResourceQuotaScopeBestEffort:
+		return isBestEffort(pod) == (requirement.Operator == corev1.ScopeSelectorOpExists), nil
+	case corev1.ResourceQuotaScopeNotBestEffort:
+		return !isBestEffort(pod) == (requirement.Operator == corev1.ScopeSelectorOpExists), nil
+	case corev1.ResourceQuotaScopePriorityClass:
+		return podMatchesPriorityClass(pod, requirement)
+	}
+	return false, fmt.Errorf("scope %s is not supported", requirement.ScopeName)
+}
+
+// podMatchesPriorityClass returns true if the pod priority class matches the scope selector requirement
+func podMatchesPriorityClass(pod *corev1.Pod, requirement corev1.ScopedResourceSelectorRequirement) (bool, error) {
+	priorityClassName := pod.Spec.PriorityClassName
+	switch requirement.Operator {
+	case corev1.ScopeSelectorOpExists:
+		return len(priorityClassName) > 0, nil
+	case corev1.ScopeSelectorOpDoesNotExist:
+		return len(priorityClassName) == 0, nil
+	case corev1.ScopeSelectorOpIn, corev1.ScopeSelectorOpNotIn:
+		found := false
+		for _, value := range requirement.Values {
+			if value == priorityClassName {
+				found = true
+				break
+			}
+		}
+		return found == (requirement.Operator == corev1.ScopeSelectorOpIn), nil
+	}
+	return false, fmt.Errorf("operator %s is not supported", requirement.Operator)
+}
+
+// isTerminating returns true if the pod has an active deadline, i.e. spec.activeDeadlineSeconds >= 0
+func isTerminating(pod *corev1.Pod) bool {
+	return pod.Spec.ActiveDeadlineSeconds != nil && *pod.Spec.ActiveDeadlineSeconds >= 0
+}
+
+// isBestEffort returns true if the pod has the BestEffort QoS class,
+// i.e. no container sets the cpu or memory requests or limits.
+func isBestEffort(pod *corev1.Pod) bool {
+	containers := append([]corev1.Container{}, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, rl := range []corev1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+			for _, resourceName := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				if quantity, found := rl[resourceName]; found && !quantity.IsZero() {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// ValidateScopes validates the scopes and the scope selector, and the resource names allowed with them
+func ValidateScopes(scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector, hard corev1.ResourceList) error {
+	requirements := ScopeRequirements(scopes, selector)
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	exists := map[corev1.ResourceQuotaScope]bool{}
+	for _, requirement := range requirements {
+		switch requirement.ScopeName {
+		case corev1.ResourceQuotaScopeTerminating, corev1.ResourceQuotaScopeNotTerminating,
+			corev1.ResourceQuotaScopeBestEffort, corev1.ResourceQuotaScopeNotBestEffort:
+			if requirement.Operator != corev1.ScopeSelectorOpExists && requirement.Operator != corev1.ScopeSelectorOpDoesNotExist {
+				return fmt.Errorf("scope %s supports the operators Exists and DoesNotExist only", requirement.ScopeName)
+			}
+		case corev1.ResourceQuotaScopePriorityClass:
+			switch requirement.Operator {
+			case corev1.ScopeSelectorOpExists, corev1.ScopeSelectorOpDoesNotExist:
+			case corev1.ScopeSelectorOpIn, corev1.ScopeSelectorOpNotIn:
+				if len(requirement.Values) == 0 {
+					return fmt.Errorf("scope %s with the operator %s requires values", requirement.ScopeName, requirement.Operator)
+				}
+				continue
+			default:
+				return fmt.Errorf("operator %s is not supported", requirement.Operator)
+			}
+		default:
+			return fmt.Errorf("scope %s is not supported", requirement.ScopeName)
+		}
+		if len(requirement.Values) > 0 {
+			return fmt.Errorf("scope %s with the operator %s must not have values", requirement.ScopeName, requirement.Operator)
+		}
+		if requirement.Operator == corev1.ScopeSelectorOpExists {
+			exists[requirement.ScopeName] = true
+		}
+	}
+
+	// the conflicting scopes match no pod
+	if exists[corev1.ResourceQuotaScopeTerminating] && exists[corev1.ResourceQuotaScopeNotTerminating] {
+		return fmt.Errorf("scopes %s and %s are mutually exclusive", corev1.ResourceQuotaScopeTerminating, corev1.ResourceQuotaScopeNotTerminating)
+	}
+	if exists[corev1.ResourceQuotaScopeBestEffort] && exists[corev1.ResourceQuotaScopeNotBestEffort] {
+		return fmt.Errorf("scopes %s and %s are mutually exclusive", corev1.ResourceQuotaScopeBestEffort, corev1.ResourceQuotaScopeNotBestEffort)
+	}
+
+	// the scopes filter the pods, the BestEffort pods have no compute resources to track
+	for resourceName := range hard {
+		if !IsPodResource(resourceName) {
+			return fmt.Errorf("resource name %s is not supported with the scopes, only the pod resources are", resourceName)
+		}
+		if exists[corev1.ResourceQuotaScopeBestEffort] && resourceName != corev1.ResourcePods {
+			return fmt.Errorf("resource name %s is not supported with the scope %s, only %s is", resourceName, corev1.ResourceQuotaScopeBestEffort, corev1.ResourcePods)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPodMatchesScopes(t *testing.T) {
+	activeDeadlineSeconds := int64(60)
+	terminating := newPod()
+	terminating.Spec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+	bestEffort := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	highPriority := newPod()
+	highPriority.Spec.PriorityClassName = "high"
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		scopes   []corev1.ResourceQuotaScope
+		selector *corev1.ScopeSelector
+		want     bool
+	}{
+		{name: "no scopes", pod: newPod(), want: true},
+		{name: "Terminating matches terminating pod", pod: terminating, scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeTerminating}, want: true},
+		{name: "Terminating does not match long-running pod", pod: newPod(), scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeTerminating}, want: false},
+		{name: "NotTerminating matches long-running pod", pod: newPod(), scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotTerminating}, want: true},
+		{name: "NotTerminating does not match terminating pod", pod: terminating, scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotTerminating}, want: false},
+		{name: "BestEffort matches best-effort pod", pod: bestEffort, scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}, want: true},
+		{name: "BestEffort does not match burstable pod", pod: newPod(), scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}, want: false},
+		{name: "NotBestEffort matches burstable pod", pod: newPod(), scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort}, want: true},
+		{name: "NotBestEffort does not match best-effort pod", pod: bestEffort, scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort}, want: false},
+		{
+			name:     "PriorityClass In matches listed priority class",
+			pod:      highPriority,
+			selector: priorityClassSelector(corev1.ScopeSelectorOpIn, "high", "medium"),
+			want:     true,
+		},
+		{
+			name:     "PriorityClass In does not match other priority class",
+			pod:      highPriority,
+			selector: priorityClassSelector(corev1.ScopeSelectorOpIn, "low"),
+			want:     false,
+		},
+		{
+			name:     "PriorityClass NotIn matches other priority class",
+			pod:      highPriority,
+			selector: priorityClassSelector(corev1.ScopeSelectorOpNotIn, "low"),
+			want:     true,
+		},
+		{
+			name:     "PriorityClass NotIn does not match listed priority class",
+			pod:      highPriority,
+			selector: priorityClassSelector(corev1.ScopeSelectorOpNotIn, "high"),
+			want:     false,
+		},
+		{
+			name:     "PriorityClass Exists matches pod with priority class",
+			pod:      highPriority,
+			selector: priorityClassSelector(corev1.ScopeSelectorOpExists),
+			want:     true,
+		},
+		{
+			name:     "PriorityClass Exists does not match pod without priority class",
+			pod:      newPod(),
+			selector: priorityClassSelector(corev1.ScopeSelectorOpExists),
+			want:     false,
+		},
+		{
+			name:     "PriorityClass DoesNotExist matches pod without priority class",
+			pod:      newPod(),
+			selector: priorityClassSelector(corev1.ScopeSelectorOpDoesNotExist),
+			want:     true,
+		},
+		{
+			name:     "PriorityClass DoesNotExist does not match pod with priority class",
+			pod:      highPriority,
+			selector: priorityClassSelector(corev1.ScopeSelectorOpDoesNotExist),
+			want:     false,
+		},
+		{
+			name:     "scopes and scope selector both matched",
+			pod:      highPriority,
+			scopes:   []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort, corev1.ResourceQuotaScopeNotTerminating},
+			selector: priorityClassSelector(corev1.ScopeSelectorOpIn, "high"),
+			want:     true,
+		},
+		{
+			name:     "scopes matched but scope selector not",
+			pod:      highPriority,
+			scopes:   []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort},
+			selector: priorityClassSelector(corev1.ScopeSelectorOpIn, "low"),
+			want:     false,
+		},
+		{
+			name:     "scope selector matched but scopes not",
+			pod:      highPriority,
+			scopes:   []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeTerminating},
+			selector: priorityClassSelector(corev1.ScopeSelectorOpIn, "high"),
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PodMatchesScopes(tt.pod, tt.scopes, tt.selector)
+			if err != nil {
+				t.Fatalf("PodMatchesScopes() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PodMatchesScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	podHard := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10"), corev1.ResourceRequestsCPU: resource.MustParse("1")}
+	tests := []struct {
+		name     string
+		scopes   []corev1.ResourceQuotaScope
+		selector *corev1.ScopeSelector
+		hard     corev1.ResourceList
+		wantErr  bool
+	}{
+		{name: "no scopes with non-pod resources", hard: corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")}},
+		{name: "scopes with pod resources", scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort}, hard: podHard},
+		{name: "scope selector with pod resources", selector: priorityClassSelector(corev1.ScopeSelectorOpIn, "high"), hard: podHard},
+		{
+			name:    "scopes with non-pod resources",
+			scopes:  []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotTerminating},
+			hard:    corev1.ResourceList{corev1.ResourceConfigMaps: resource.MustParse("1")},
+			wantErr: true,
+		},
+		{
+			name:     "scope selector with non-pod resources",
+			selector: priorityClassSelector(corev1.ScopeSelectorOpExists),
+			hard:     corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("10Gi")},
+			wantErr:  true,
+		},
+		{
+			name:    "BestEffort with compute resources",
+			scopes:  []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			hard:    podHard,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scope",
+			scopes:  []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeCrossNamespacePodAffinity},
+			hard:    podHard,
+			wantErr: true,
+		},
+		{
+			name:    "mutually exclusive scopes",
+			scopes:  []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeTerminating, corev1.ResourceQuotaScopeNotTerminating},
+			hard:    podHard,
+			wantErr: true,
+		},
+		{
+			name:     "In without values",
+			selector: priorityClassSelector(corev1.ScopeSelectorOpIn),
+			hard:     podHard,
+			wantErr:  true,
+		},
+		{
+			name: "In with the non-PriorityClass scope",
+			selector: &corev1.ScopeSelector{MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+				ScopeName: corev1.ResourceQuotaScopeBestEffort,
+				Operator:  corev1.ScopeSelectorOpIn,
+				Values:    []string{"high"},
+			}}},
+			hard:    podHard,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateScopes(tt.scopes, tt.selector, tt.hard)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// priorityClassSelector returns the scope selector of the PriorityClass scope
+func priorityClassSelector(operator corev1.ScopeSelectorOperator, values ...string) *corev1.ScopeSelector {
+	return &corev1.ScopeSelector{MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+		ScopeName: corev1.ResourceQuotaScopePriorityClass,
+		Operator:  operator,
+		Values:    values,
+	}}}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 JenTing.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsServiceResource returns true if the resource name is tracked by the Services
+func IsServiceResource(resourceName corev1.ResourceName) bool {
+	switch resourceName {
+	case corev1.ResourceServices, corev1.ResourceServicesNodePorts, corev1.ResourceServicesLoadBalancers:
+		return true
+	}
+	return false
+}
+
+// ServiceUsage returns the resource usage of the Service, the count, the load balancers and the node ports.
+// The NodePort Service allocates a node port per port, and the LoadBalancer Service as well unless
+// spec.allocateLoadBalancerNodePorts is false, in which case only the ports with an explicit node port are counted.
+func ServiceUsage(svc *corev1.Service) corev1.ResourceList {
+	usage := corev1.ResourceList{corev1.ResourceServices: resource.MustParse("1")}
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeNodePort:
+		usage[corev1.ResourceServicesNodePorts] = *resource.NewQuantity(int64(len(svc.Spec.Ports)), resource.DecimalSI)
+	case corev1.ServiceTypeLoadBalancer:
+		usage[corev1.ResourceServicesLoadBalancers] = resource.MustParse("1")
+		if svc.Spec.AllocateLoadBalancerNodePorts != nil && !*svc.Spec.AllocateLoadBalancerNodePorts {
+			var nodePorts int64
+			for _, port := range svc.Spec.Ports {
+				if port.NodePort != 0 {
+					nodePorts++
+				}
+			}
+			usage[corev1.ResourceServicesNodePorts] = *resource.NewQuantity(nodePorts, resource.DecimalSI)
+		} else {
+			usage[corev1.ResourceServicesNodePorts] = *resource.NewQuantity(int64(len(svc.Spec.Ports)), resource.DecimalSI)
+		}
+	}
+	return usage
+}
+
+// serviceEvaluator evaluates the Services
+type serviceEvaluator struct{}
+
+func (e *serviceEvaluator) Kind() string {
+	return "Service"
+}
+
+func (e *serviceEvaluator) NewObject() client.Object {
+	return &corev1.Service{}
+}
+
+func (e *serviceEvaluator) NewList() client.ObjectList {
+	return &corev1.ServiceList{}
+}
+
+func (e *serviceEvaluator) Handles(resourceName corev1.ResourceName) bool {
+	return IsServiceResource(resourceName)
+}
+
+func (e *serviceEvaluator) Matches(obj client.Object, scopes []corev1.ResourceQuotaScope, selector *corev1.ScopeSelector) (bool, error) {
+	return true, nil
+}
+
+func (e *serviceEvaluator) Usage(obj client.Object) corev1.ResourceList {
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return corev1.ResourceList{}
+	}
+	return ServiceUsage(svc)
+}